@@ -0,0 +1,356 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/jacobsee/modcal/internal/calendar"
+	"github.com/jacobsee/modcal/internal/config"
+	"github.com/jacobsee/modcal/internal/logging"
+	"github.com/jacobsee/modcal/internal/models"
+	"github.com/jacobsee/modcal/internal/plugin"
+	"github.com/jacobsee/modcal/internal/scheduler"
+	"github.com/jacobsee/modcal/internal/store"
+	"github.com/jacobsee/modcal/internal/tokens"
+	"github.com/jacobsee/modcal/internal/validate"
+
+	// Plugins
+	"github.com/jacobsee/modcal/plugins/anilist"
+	"github.com/jacobsee/modcal/plugins/example"
+	"github.com/jacobsee/modcal/plugins/ics"
+	"github.com/jacobsee/modcal/plugins/mal"
+	"github.com/jacobsee/modcal/plugins/trakt"
+)
+
+// app holds what the commands build from the config
+type app struct {
+	cfg        *config.Config
+	logger     *slog.Logger
+	calManager *calendar.Manager
+	tokens     tokens.Store
+}
+
+// loadApp loads the config and sets up its plugins and calendars. quiet
+// raises the default log level to warn, for commands whose output goes to a
+// terminal.
+func loadApp(configPath string, quiet bool) (*app, error) {
+	registry, err := newRegistry()
+	if err != nil {
+		return nil, fmt.Errorf("failed to register plugins: %w", err)
+	}
+
+	cfg, issues, err := validate.File(configPath, registry, validationChecks(registry))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load config: %w", err)
+	}
+	if validate.HasErrors(issues) {
+		return nil, fmt.Errorf("invalid config, run 'modcal validate' for details:\n%s", formatIssues(issues))
+	}
+
+	level := cfg.Logging.Level
+	if quiet && level == "info" {
+		level = "warn"
+	}
+	logger, err := logging.New(os.Stderr, level, cfg.Logging.Format)
+	if err != nil {
+		return nil, fmt.Errorf("invalid logging config: %w", err)
+	}
+	slog.SetDefault(logger)
+	for _, issue := range issues {
+		logger.Warn("Config warning", "issue", issue.String())
+	}
+
+	tokenStore, err := tokens.NewStore(cfg.Tokens.Path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open token store: %w", err)
+	}
+
+	pluginManager := calendar.NewPluginManager()
+	if err := initializePlugins(cfg, registry, pluginManager, tokenStore, logger); err != nil {
+		return nil, fmt.Errorf("failed to initialize plugins: %w", err)
+	}
+
+	eventStore, err := store.NewStore(cfg.Storage.Type, cfg.Storage.Path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open storage: %w", err)
+	}
+
+	calManager := calendar.NewManager(pluginManager, eventStore, logger)
+	for _, calCfg := range cfg.Calendars {
+		definition, err := buildCalendar(calCfg)
+		if err != nil {
+			return nil, err
+		}
+		calManager.AddCalendar(definition)
+	}
+
+	return &app{cfg: cfg, logger: logger, calManager: calManager, tokens: tokenStore}, nil
+}
+
+// validationChecks checks plugins and calendars by building them the way
+// loadApp does, without side effects
+func validationChecks(registry *plugin.Registry) validate.Checks {
+	return validate.Checks{
+		Plugin: func(pluginCfg config.PluginConfig) error {
+			logger := slog.New(slog.DiscardHandler)
+			if _, _, err := createPlugin(registry, pluginCfg, tokens.NewMemoryStore(), logger); err != nil {
+				return err
+			}
+			// The default interval is valid, only the plugin's own schedule matters
+			_, err := buildSchedule(pluginCfg, time.Minute)
+			return err
+		},
+		Calendar: func(calCfg config.CalendarConfig) error {
+			_, err := buildCalendar(calCfg)
+			return err
+		},
+	}
+}
+
+// formatIssues lists issues one per line
+func formatIssues(issues []validate.Issue) string {
+	lines := make([]string, len(issues))
+	for i, issue := range issues {
+		lines[i] = "  " + issue.String()
+	}
+	return strings.Join(lines, "\n")
+}
+
+func newRegistry() (*plugin.Registry, error) {
+	registry := plugin.NewRegistry()
+	plugins := []plugin.Plugin{
+		example.New(),
+		trakt.New(),
+		anilist.New(),
+		mal.New(),
+		ics.New(),
+	}
+
+	for _, p := range plugins {
+		if err := registry.Register(p); err != nil {
+			return nil, err
+		}
+	}
+
+	return registry, nil
+}
+
+func initializePlugins(cfg *config.Config, registry *plugin.Registry, pm *calendar.PluginManager, tokenStore tokens.Store, logger *slog.Logger) error {
+	for _, pluginCfg := range cfg.Plugins {
+		pluginLogger := logging.ForPlugin(logger, pluginCfg.ID, pluginCfg.Type)
+		instance, opts, err := createPlugin(registry, pluginCfg, tokenStore, pluginLogger)
+		if err != nil {
+			return err
+		}
+
+		pm.AddInstance(pluginCfg.ID, instance, opts)
+		pluginLogger.Info("Initialized plugin")
+	}
+
+	return nil
+}
+
+// createPlugin creates a configured plugin instance
+func createPlugin(registry *plugin.Registry, pluginCfg config.PluginConfig, tokenStore tokens.Store, logger *slog.Logger) (plugin.Plugin, calendar.InstanceOptions, error) {
+	var opts calendar.InstanceOptions
+	template, err := registry.Get(pluginCfg.Type)
+	if err != nil {
+		return nil, opts, err
+	}
+
+	instance, err := template.Create(pluginCfg.Config, plugin.Env{
+		ID:     pluginCfg.ID,
+		Logger: logger,
+		Tokens: tokenStore,
+	})
+	if err != nil {
+		return nil, opts, fmt.Errorf("failed to create plugin %s: %w", pluginCfg.ID, err)
+	}
+
+	if opts.Alarms, err = buildAlarms(pluginCfg.Alarms); err != nil {
+		return nil, opts, fmt.Errorf("invalid alarms for plugin %s: %w", pluginCfg.ID, err)
+	}
+	if opts.Spoilers, err = buildSpoilers(pluginCfg.SpoilerFree); err != nil {
+		return nil, opts, fmt.Errorf("invalid spoilerFree for plugin %s: %w", pluginCfg.ID, err)
+	}
+
+	return instance, opts, nil
+}
+
+func buildCalendar(calCfg config.CalendarConfig) (*calendar.CalendarDefinition, error) {
+	alarms, err := buildAlarms(calCfg.Alarms)
+	if err != nil {
+		return nil, fmt.Errorf("invalid alarms for calendar %s: %w", calCfg.Name, err)
+	}
+	if calCfg.TimeZone != "" {
+		if _, err := models.LoadZone(calCfg.TimeZone); err != nil {
+			return nil, fmt.Errorf("invalid timezone for calendar %s: %w", calCfg.Name, err)
+		}
+	}
+	rules, err := buildRules(calCfg.Rules)
+	if err != nil {
+		return nil, fmt.Errorf("invalid rules for calendar %s: %w", calCfg.Name, err)
+	}
+	dedup, err := buildDedup(calCfg)
+	if err != nil {
+		return nil, fmt.Errorf("invalid dedup for calendar %s: %w", calCfg.Name, err)
+	}
+	spoilers, err := buildSpoilers(calCfg.SpoilerFree)
+	if err != nil {
+		return nil, fmt.Errorf("invalid spoilerFree for calendar %s: %w", calCfg.Name, err)
+	}
+
+	return &calendar.CalendarDefinition{
+		Name:        calCfg.Name,
+		Description: calCfg.Description,
+		PluginIDs:   calCfg.PluginIDs,
+		Alarms:      alarms,
+		TimeZone:    calCfg.TimeZone,
+		Rules:       rules,
+		Dedup:       dedup,
+		Spoilers:    spoilers,
+	}, nil
+}
+
+func buildAlarms(alarmCfgs []config.AlarmConfig) ([]calendar.AlarmSpec, error) {
+	var alarms []calendar.AlarmSpec
+	for _, alarmCfg := range alarmCfgs {
+		alarm, err := calendar.NewAlarmSpec(alarmCfg.Before, alarmCfg.At, alarmCfg.DaysBefore, alarmCfg.Description)
+		if err != nil {
+			return nil, err
+		}
+		alarms = append(alarms, alarm)
+	}
+	return alarms, nil
+}
+
+func buildRules(ruleCfgs []config.RuleConfig) ([]calendar.Rule, error) {
+	var rules []calendar.Rule
+	for i, ruleCfg := range ruleCfgs {
+		var rule calendar.Rule
+		var err error
+
+		if rule.Include, err = buildMatch(ruleCfg.Include); err != nil {
+			return nil, fmt.Errorf("rule %d include: %w", i+1, err)
+		}
+		if rule.Exclude, err = buildMatch(ruleCfg.Exclude); err != nil {
+			return nil, fmt.Errorf("rule %d exclude: %w", i+1, err)
+		}
+		if rule.When, err = buildMatch(ruleCfg.When); err != nil {
+			return nil, fmt.Errorf("rule %d when: %w", i+1, err)
+		}
+
+		for _, rewriteCfg := range ruleCfg.Rewrite {
+			rewrite, err := calendar.NewRewrite(rewriteCfg.Pattern, rewriteCfg.Replace)
+			if err != nil {
+				return nil, fmt.Errorf("rule %d: %w", i+1, err)
+			}
+			rule.Rewrites = append(rule.Rewrites, rewrite)
+		}
+		rule.Prefix = ruleCfg.Prefix
+		if len(ruleCfg.Categories) > 0 {
+			if rule.RemapCategories, err = calendar.NewRemap(ruleCfg.Categories); err != nil {
+				return nil, fmt.Errorf("rule %d categories: %w", i+1, err)
+			}
+		}
+
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+func buildMatch(matchCfg *config.MatchConfig) (*calendar.Match, error) {
+	if matchCfg == nil {
+		return nil, nil
+	}
+	return calendar.NewMatch(matchCfg.Categories, matchCfg.Summary, matchCfg.Shows, matchCfg.Between)
+}
+
+func buildDedup(calCfg config.CalendarConfig) (*calendar.DedupOptions, error) {
+	if calCfg.Dedup == nil {
+		return nil, nil
+	}
+
+	plugins := make(map[string]bool)
+	for _, id := range calCfg.PluginIDs {
+		plugins[id] = true
+	}
+	check := func(ids []string) error {
+		for _, id := range ids {
+			if !plugins[id] {
+				return fmt.Errorf("plugin %s is not part of the calendar", id)
+			}
+		}
+		return nil
+	}
+
+	if err := check(calCfg.Dedup.Prefer); err != nil {
+		return nil, err
+	}
+	for _, ids := range calCfg.Dedup.Fields {
+		if err := check(ids); err != nil {
+			return nil, err
+		}
+	}
+
+	return calendar.NewDedupOptions(calCfg.Dedup.Window, calCfg.Dedup.Prefer, calCfg.Dedup.Fields)
+}
+
+func buildSpoilers(spoilerCfg *config.SpoilerConfig) (*calendar.SpoilerOptions, error) {
+	if spoilerCfg == nil {
+		return nil, nil
+	}
+	if spoilerCfg.RevealDelay < 0 {
+		return nil, fmt.Errorf("revealDelay must not be negative")
+	}
+	return &calendar.SpoilerOptions{
+		Enabled:     spoilerCfg.Enabled,
+		Fields:      spoilerCfg.Fields,
+		Reveal:      spoilerCfg.Reveal,
+		RevealDelay: spoilerCfg.RevealDelay,
+	}, nil
+}
+
+func buildScheduler(cfg *config.Config, calManager *calendar.Manager, logger *slog.Logger) (*scheduler.Scheduler, error) {
+	sched := scheduler.New(calManager.RefreshPlugin, *cfg.Scheduler.Jitter, logger)
+
+	for _, pluginCfg := range cfg.Plugins {
+		schedule, err := buildSchedule(pluginCfg, cfg.Scheduler.Interval)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := sched.Add(pluginCfg.ID, schedule); err != nil {
+			return nil, err
+		}
+		if next, ok := sched.NextRun(pluginCfg.ID); ok {
+			logger.Info("Scheduled plugin", "plugin_id", pluginCfg.ID, "next_run", next)
+		}
+	}
+
+	return sched, nil
+}
+
+// buildSchedule returns the refresh schedule of a plugin, defaultInterval
+// if it has none of its own
+func buildSchedule(pluginCfg config.PluginConfig, defaultInterval time.Duration) (scheduler.Schedule, error) {
+	switch {
+	case pluginCfg.Schedule != "" && pluginCfg.Interval != 0:
+		return nil, fmt.Errorf("plugin %s: interval and schedule cannot be combined", pluginCfg.ID)
+	case pluginCfg.Schedule != "":
+		cron, err := scheduler.ParseCron(pluginCfg.Schedule)
+		if err != nil {
+			return nil, fmt.Errorf("plugin %s: %w", pluginCfg.ID, err)
+		}
+		return cron, nil
+	case pluginCfg.Interval < 0:
+		return nil, fmt.Errorf("plugin %s: interval must not be negative", pluginCfg.ID)
+	case pluginCfg.Interval > 0:
+		return scheduler.Interval(pluginCfg.Interval), nil
+	default:
+		return scheduler.Interval(defaultInterval), nil
+	}
+}
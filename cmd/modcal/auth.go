@@ -0,0 +1,68 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+
+	"github.com/jacobsee/modcal/internal/plugin"
+)
+
+func runAuth(args []string) error {
+	fs, configPath := newFlagSet("auth", "<plugin-id>")
+	redirectURI := fs.String("redirect-uri", "http://localhost", "Redirect URL of the app, for flows that redirect and have no redirectUri configured")
+	positional, err := parseArgs(fs, args, 1)
+	if err != nil {
+		return err
+	}
+	id := positional[0]
+
+	a, err := loadApp(*configPath, true)
+	if err != nil {
+		return err
+	}
+	if a.cfg.Tokens.Path == "" {
+		return fmt.Errorf("tokens.path must be set in the config to save the tokens")
+	}
+
+	p, ok := a.calManager.Plugin(id)
+	if !ok {
+		return fmt.Errorf("plugin %s not found", id)
+	}
+	authorizer, ok := p.(plugin.Authorizer)
+	if !ok {
+		return fmt.Errorf("plugin %s (%s) does not use OAuth", id, p.Name())
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	flow, err := authorizer.StartAuth(ctx, *redirectURI)
+	if err != nil {
+		return err
+	}
+
+	if flow.Device() {
+		fmt.Printf("Open %s and enter the code %s\n\nWaiting for approval...\n", flow.URL, flow.UserCode)
+		err = flow.Finish(ctx, "")
+	} else {
+		fmt.Printf("Open this URL to authorize modcal:\n\n  %s\n\nThen paste the code or the URL you were redirected to: ", flow.URL)
+		line, readErr := bufio.NewReader(os.Stdin).ReadString('\n')
+		if readErr != nil && line == "" {
+			return fmt.Errorf("failed to read code: %w", readErr)
+		}
+		code, codeErr := flow.Code(line)
+		if codeErr != nil {
+			return codeErr
+		}
+		err = flow.Finish(ctx, code)
+	}
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("\nAuthorized %s, the tokens were saved to %s.\nA running server uses them from its next refresh.\n", id, a.cfg.Tokens.Path)
+	return nil
+}
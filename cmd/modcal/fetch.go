@@ -0,0 +1,69 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/jacobsee/modcal/internal/jsoncal"
+	"github.com/jacobsee/modcal/internal/models"
+)
+
+func runFetch(args []string) error {
+	fs, configPath := newFlagSet("fetch", "<plugin-id>")
+	asJSON := fs.Bool("json", false, "Print the events as JSON")
+	positional, err := parseArgs(fs, args, 1)
+	if err != nil {
+		return err
+	}
+	id := positional[0]
+
+	a, err := loadApp(*configPath, true)
+	if err != nil {
+		return err
+	}
+	p, ok := a.calManager.Plugin(id)
+	if !ok {
+		return fmt.Errorf("plugin %s not found", id)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	events, err := p.FetchEvents(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to fetch events: %w", err)
+	}
+
+	if *asJSON {
+		data, err := jsoncal.Format(&models.Calendar{Name: id, Events: events})
+		if err != nil {
+			return err
+		}
+		_, err = os.Stdout.Write(data)
+		return err
+	}
+	return printEvents(os.Stdout, events)
+}
+
+// printEvents lists events by start time, in the local zone
+func printEvents(w io.Writer, events []models.Event) error {
+	sorted := append([]models.Event(nil), events...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].StartTime.Before(sorted[j].StartTime) })
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for _, event := range sorted {
+		start := event.StartTime.Local().Format("2006-01-02 15:04")
+		if event.AllDay {
+			start = event.StartTime.Format("2006-01-02")
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", start, event.Summary, strings.Join(event.Categories, ", "))
+	}
+	fmt.Fprintf(tw, "\n%d events\n", len(sorted))
+	return tw.Flush()
+}
@@ -1,114 +1,110 @@
 package main
 
 import (
-	"context"
+	"errors"
 	"flag"
 	"fmt"
-	"log"
-	"time"
-
-	"github.com/jacobsee/modcal/internal/auth"
-	"github.com/jacobsee/modcal/internal/calendar"
-	"github.com/jacobsee/modcal/internal/config"
-	"github.com/jacobsee/modcal/internal/plugin"
-	"github.com/jacobsee/modcal/internal/server"
-
-	// Plugins
-	"github.com/jacobsee/modcal/plugins/anilist"
-	"github.com/jacobsee/modcal/plugins/example"
-	"github.com/jacobsee/modcal/plugins/mal"
-	"github.com/jacobsee/modcal/plugins/trakt"
+	"os"
+	"strings"
+	_ "time/tzdata" // Embedded so time zones work without system tzdata
 )
 
-func main() {
-	configPath := flag.String("config", "config.yaml", "Path to configuration file")
-	flag.Parse()
+// command is a subcommand of modcal
+type command struct {
+	name    string
+	args    string // Positional arguments, for usage
+	summary string
+	run     func(args []string) error
+}
 
-	cfg, err := config.LoadFromFile(*configPath)
-	if err != nil {
-		log.Fatalf("Failed to load config: %v", err)
-	}
+var commands = []command{
+	{"serve", "", "Run the calendar server (default)", runServe},
+	{"auth", "<plugin-id>", "Authorize a plugin instance and save its tokens", runAuth},
+	{"validate", "", "Check the configuration", runValidate},
+	{"fetch", "<plugin-id>", "Fetch and print the events of a plugin instance", runFetch},
+	{"render", "<calendar>", "Fetch the events of a calendar and write it", runRender},
+	{"plugins", "", "List plugin types and their config options", runPlugins},
+}
 
-	registry := plugin.NewRegistry()
-	if err := registerPlugins(registry); err != nil {
-		log.Fatalf("Failed to register plugins: %v", err)
-	}
+// errUsage is returned by commands called with wrong arguments, after they
+// printed their usage
+var errUsage = errors.New("invalid arguments")
 
-	pluginManager := calendar.NewPluginManager()
-	if err := initializePlugins(cfg, registry, pluginManager); err != nil {
-		log.Fatalf("Failed to initialize plugins: %v", err)
+func main() {
+	args := os.Args[1:]
+	if len(args) > 0 && (args[0] == "help" || isHelp(args[0])) {
+		usage()
+		return
 	}
 
-	calManager := calendar.NewManager(pluginManager)
-	for _, calCfg := range cfg.Calendars {
-		calManager.AddCalendar(&calendar.CalendarDefinition{
-			Name:        calCfg.Name,
-			Description: calCfg.Description,
-			PluginIDs:   calCfg.PluginIDs,
-		})
+	// Without a command, modcal serves, so "modcal -config ..." keeps working
+	name := "serve"
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		name, args = args[0], args[1:]
 	}
 
-	log.Println("Performing initial event fetch...")
-	if err := calManager.RefreshEvents(context.Background()); err != nil {
-		log.Printf("Warning: Initial event fetch failed: %v", err)
+	for _, cmd := range commands {
+		if cmd.name != name {
+			continue
+		}
+		err := cmd.run(args)
+		if errors.Is(err, errUsage) {
+			os.Exit(2)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "modcal %s: %v\n", name, err)
+			os.Exit(1)
+		}
+		return
 	}
 
-	go startScheduler(calManager, cfg.Scheduler.Interval)
-
-	authenticator := auth.NewAuthenticator(cfg.Auth.Method, cfg.Auth.APIKey)
-	srv := server.New(calManager, authenticator, cfg.Server.Host, cfg.Server.Port)
+	fmt.Fprintf(os.Stderr, "modcal: unknown command %q\n\n", name)
+	usage()
+	os.Exit(2)
+}
 
-	log.Fatal(srv.Start())
+func isHelp(arg string) bool {
+	return arg == "-h" || arg == "-help" || arg == "--help"
 }
 
-func registerPlugins(registry *plugin.Registry) error {
-	plugins := []plugin.Plugin{
-		example.New(),
-		trakt.New(),
-		anilist.New(),
-		mal.New(),
+func usage() {
+	fmt.Fprintf(os.Stderr, "Usage: modcal <command> [flags] [arguments]\n\nCommands:\n")
+	for _, cmd := range commands {
+		fmt.Fprintf(os.Stderr, "  %-22s %s\n", strings.TrimSpace(cmd.name+" "+cmd.args), cmd.summary)
 	}
+	fmt.Fprintf(os.Stderr, "\nRun 'modcal <command> -h' for the flags of a command.\n")
+}
 
-	for _, p := range plugins {
-		if err := registry.Register(p); err != nil {
-			return err
-		}
+// newFlagSet creates the flag set of a command, with the -config flag every
+// command takes
+func newFlagSet(name, args string) (*flag.FlagSet, *string) {
+	fs := flag.NewFlagSet(name, flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: modcal %s [flags] %s\n\nFlags:\n", name, args)
+		fs.PrintDefaults()
 	}
-
-	return nil
+	configPath := fs.String("config", "config.yaml", "Path to configuration file")
+	return fs, configPath
 }
 
-func initializePlugins(cfg *config.Config, registry *plugin.Registry, pm *calendar.PluginManager) error {
-	for _, pluginCfg := range cfg.Plugins {
-		template, err := registry.Get(pluginCfg.Type)
-		if err != nil {
-			return err
-		}
-
-		instance, err := template.Create(pluginCfg.Config)
-		if err != nil {
-			return fmt.Errorf("failed to create plugin %s: %w", pluginCfg.ID, err)
+// parseArgs parses flags before and after positional arguments, so both
+// "auth -config x.yaml trakt" and "auth trakt -config x.yaml" work. It
+// returns an error if the number of positional arguments isn't n.
+func parseArgs(fs *flag.FlagSet, args []string, n int) ([]string, error) {
+	var positional []string
+	for {
+		fs.Parse(args) // Exits on error
+		args = fs.Args()
+		if len(args) == 0 {
+			break
 		}
-
-		pm.AddInstance(pluginCfg.ID, instance)
-		log.Printf("Initialized plugin: %s (type: %s)", pluginCfg.ID, pluginCfg.Type)
+		positional = append(positional, args[0])
+		args = args[1:]
 	}
 
-	return nil
-}
-
-func startScheduler(calManager *calendar.Manager, interval time.Duration) {
-	ticker := time.NewTicker(interval)
-	defer ticker.Stop()
-
-	log.Printf("Starting event refresh scheduler (interval: %v)", interval)
-
-	for range ticker.C {
-		log.Println("Refreshing events from all plugins...")
-		if err := calManager.RefreshEvents(context.Background()); err != nil {
-			log.Printf("Error refreshing events: %v", err)
-		} else {
-			log.Println("Events refreshed successfully")
-		}
+	if len(positional) != n {
+		fs.Usage()
+		return nil, errUsage
 	}
+	return positional, nil
 }
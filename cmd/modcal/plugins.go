@@ -0,0 +1,121 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/jacobsee/modcal/internal/plugin"
+)
+
+// pluginInfo describes a plugin type for the json format
+type pluginInfo struct {
+	Type      string          `json:"type"`
+	Authorize bool            `json:"authorize"` // Implements plugin.Authorizer
+	Options   []plugin.Option `json:"options"`
+}
+
+func runPlugins(args []string) error {
+	fs := flag.NewFlagSet("plugins", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: modcal plugins [flags]\n\nFlags:\n")
+		fs.PrintDefaults()
+	}
+	format := fs.String("format", "text", "Output format: text, markdown or json")
+	if _, err := parseArgs(fs, args, 0); err != nil {
+		return err
+	}
+
+	registry, err := newRegistry()
+	if err != nil {
+		return err
+	}
+	names := registry.List()
+	sort.Strings(names)
+
+	var infos []pluginInfo
+	for _, name := range names {
+		p, err := registry.Get(name)
+		if err != nil {
+			return err
+		}
+		_, authorize := p.(plugin.Authorizer)
+		infos = append(infos, pluginInfo{Type: name, Authorize: authorize, Options: p.Schema().Options})
+	}
+
+	switch *format {
+	case "text":
+		return printPluginsText(os.Stdout, infos)
+	case "markdown":
+		return printPluginsMarkdown(os.Stdout, infos)
+	case "json":
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(infos)
+	default:
+		fs.Usage()
+		return errUsage
+	}
+}
+
+func printPluginsText(w io.Writer, infos []pluginInfo) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for i, info := range infos {
+		if i > 0 {
+			fmt.Fprintln(tw)
+		}
+		fmt.Fprintln(tw, info.Type)
+		if info.Authorize {
+			fmt.Fprintf(tw, "  Authorize with: modcal auth <plugin-id>\n")
+		}
+		for _, opt := range info.Options {
+			fmt.Fprintf(tw, "  %s\t%s\t%s\t%s\n", opt.Name, opt.Type, optionNotes(opt), opt.Description)
+		}
+	}
+	return tw.Flush()
+}
+
+// printPluginsMarkdown writes a table of options per plugin type, for
+// plugin READMEs
+func printPluginsMarkdown(out io.Writer, infos []pluginInfo) error {
+	w := bufio.NewWriter(out)
+	for i, info := range infos {
+		if i > 0 {
+			fmt.Fprintln(w)
+		}
+		fmt.Fprintf(w, "## %s\n\n", info.Type)
+		if info.Authorize {
+			fmt.Fprintf(w, "Authorize with `modcal auth <plugin-id>` or at `/auth/<plugin-id>`.\n\n")
+		}
+		fmt.Fprintf(w, "| Option | Type | Notes | Description |\n|---|---|---|---|\n")
+		for _, opt := range info.Options {
+			fmt.Fprintf(w, "| `%s` | %s | %s | %s |\n", opt.Name, opt.Type, optionNotes(opt), opt.Description)
+		}
+	}
+	return w.Flush()
+}
+
+// optionNotes lists whether an option is required or secret and its default
+func optionNotes(opt plugin.Option) string {
+	var notes []string
+	if opt.Required {
+		notes = append(notes, "required")
+	}
+	if opt.Secret {
+		notes = append(notes, "secret")
+	}
+	switch def := opt.Default.(type) {
+	case nil:
+	case []string:
+		notes = append(notes, fmt.Sprintf("default: [%s]", strings.Join(def, ", ")))
+	default:
+		notes = append(notes, fmt.Sprintf("default: %v", def))
+	}
+	return strings.Join(notes, ", ")
+}
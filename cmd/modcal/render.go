@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+
+	"github.com/jacobsee/modcal/internal/agenda"
+	"github.com/jacobsee/modcal/internal/config"
+	"github.com/jacobsee/modcal/internal/ical"
+	"github.com/jacobsee/modcal/internal/jsoncal"
+	"github.com/jacobsee/modcal/internal/models"
+)
+
+// renderFormats are the output formats of the render command
+var renderFormats = map[string]func(cal *models.Calendar) ([]byte, error){
+	"ics": func(cal *models.Calendar) ([]byte, error) {
+		return []byte(ical.Format(cal)), nil
+	},
+	"json": jsoncal.Format,
+	"jcal": ical.FormatJCal,
+	"html": func(cal *models.Calendar) ([]byte, error) {
+		return agenda.Render(cal, agenda.Options{})
+	},
+}
+
+func runRender(args []string) error {
+	fs, configPath := newFlagSet("render", "<calendar>")
+	format := fs.String("format", "ics", "Output format: "+strings.Join(renderFormatNames(), ", "))
+	output := fs.String("o", "", "Write the calendar to this file instead of stdout")
+	cached := fs.Bool("cached", false, "Render the saved events instead of fetching them")
+	positional, err := parseArgs(fs, args, 1)
+	if err != nil {
+		return err
+	}
+	name := positional[0]
+
+	render, ok := renderFormats[*format]
+	if !ok {
+		return fmt.Errorf("unknown format %q", *format)
+	}
+
+	a, err := loadApp(*configPath, true)
+	if err != nil {
+		return err
+	}
+	calCfg, ok := findCalendar(a.cfg, name)
+	if !ok {
+		return fmt.Errorf("calendar %s not found", name)
+	}
+
+	if _, err := a.calManager.LoadEvents(); err != nil {
+		a.logger.Warn("Failed to load saved events", "error", err)
+	}
+	if !*cached {
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer stop()
+
+		// Failures are logged by the manager, the saved events of failing
+		// plugins are rendered instead
+		for _, id := range calCfg.PluginIDs {
+			a.calManager.RefreshPlugin(ctx, id)
+		}
+	}
+
+	cal, err := a.calManager.GetCalendar(name)
+	if err != nil {
+		return err
+	}
+	data, err := render(cal)
+	if err != nil {
+		return fmt.Errorf("failed to render calendar: %w", err)
+	}
+
+	if *output == "" {
+		_, err = os.Stdout.Write(data)
+		return err
+	}
+	return os.WriteFile(*output, data, 0o644)
+}
+
+func findCalendar(cfg *config.Config, name string) (config.CalendarConfig, bool) {
+	for _, calCfg := range cfg.Calendars {
+		if calCfg.Name == name {
+			return calCfg, true
+		}
+	}
+	return config.CalendarConfig{}, false
+}
+
+func renderFormatNames() []string {
+	names := make([]string, 0, len(renderFormats))
+	for name := range renderFormats {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"os"
+
+	"github.com/jacobsee/modcal/internal/auth"
+	"github.com/jacobsee/modcal/internal/metrics"
+	"github.com/jacobsee/modcal/internal/server"
+)
+
+func runServe(args []string) error {
+	fs, configPath := newFlagSet("serve", "")
+	if _, err := parseArgs(fs, args, 0); err != nil {
+		return err
+	}
+
+	a, err := loadApp(*configPath, false)
+	if err != nil {
+		return err
+	}
+	logger, calManager := a.logger, a.calManager
+	calManager.RegisterMetrics(metrics.Default)
+
+	loaded, err := calManager.LoadEvents()
+	if err != nil {
+		logger.Warn("Failed to load saved events", "error", err)
+	}
+
+	// Failures of single plugins are logged by the manager
+	if loaded > 0 {
+		// Saved events are served while the initial fetch runs
+		logger.Info("Performing initial event fetch in background", "plugins_loaded", loaded)
+		go calManager.RefreshEvents(context.Background())
+	} else {
+		logger.Info("Performing initial event fetch")
+		calManager.RefreshEvents(context.Background())
+	}
+
+	sched, err := buildScheduler(a.cfg, calManager, logger)
+	if err != nil {
+		fatal(logger, "Failed to schedule plugins", "error", err)
+	}
+	go sched.Run(context.Background())
+
+	authenticator := auth.NewAuthenticator(a.cfg.Auth.Method, a.cfg.Auth.APIKey)
+	srv := server.New(calManager, authenticator, a.tokens, a.cfg.Server.Host, a.cfg.Server.Port, logger)
+
+	fatal(logger, "Server stopped", "error", srv.Start())
+	return nil
+}
+
+// fatal logs an error and exits
+func fatal(logger *slog.Logger, msg string, args ...any) {
+	logger.Error(msg, args...)
+	os.Exit(1)
+}
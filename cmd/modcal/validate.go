@@ -0,0 +1,49 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/jacobsee/modcal/internal/validate"
+)
+
+func runValidate(args []string) error {
+	fs, configPath := newFlagSet("validate", "")
+	if _, err := parseArgs(fs, args, 0); err != nil {
+		return err
+	}
+
+	registry, err := newRegistry()
+	if err != nil {
+		return fmt.Errorf("failed to register plugins: %w", err)
+	}
+	_, issues, err := validate.File(*configPath, registry, validationChecks(registry))
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	errors := 0
+	for _, issue := range issues {
+		fmt.Println(issue)
+		if issue.Severity == validate.Error {
+			errors++
+		}
+	}
+	if errors > 0 {
+		return fmt.Errorf("%s and %s in %s", count(errors, "error"), count(len(issues)-errors, "warning"), *configPath)
+	}
+
+	if len(issues) > 0 {
+		fmt.Printf("%s is valid, with %s\n", *configPath, count(len(issues), "warning"))
+	} else {
+		fmt.Printf("%s is valid\n", *configPath)
+	}
+	return nil
+}
+
+// count formats n of something, e.g. "1 error" or "2 errors"
+func count(n int, noun string) string {
+	if n == 1 {
+		return "1 " + noun
+	}
+	return fmt.Sprintf("%d %ss", n, noun)
+}
@@ -0,0 +1,173 @@
+package agenda
+
+import (
+	"bytes"
+	"embed"
+	"html/template"
+	"sort"
+	"time"
+
+	"github.com/jacobsee/modcal/internal/models"
+	"github.com/jacobsee/modcal/internal/recurrence"
+)
+
+//go:embed templates/*.html
+var templateFS embed.FS
+
+var agendaTemplate = template.Must(template.ParseFS(templateFS, "templates/agenda.html"))
+
+// The agenda lists events from the start of today up to this far ahead
+const window = 30 * 24 * time.Hour
+
+// Options describes links shown on the agenda page
+type Options struct {
+	SubscribeURL string // URL calendar apps can subscribe to, if set
+}
+
+type page struct {
+	Title        string
+	Description  string
+	TimeZone     string
+	SubscribeURL string
+	Days         []day
+}
+
+type day struct {
+	Label  string
+	Date   string
+	Events []entry
+}
+
+type entry struct {
+	Time        string
+	Summary     string
+	Description string
+	Location    string
+	URL         string
+	Categories  []string
+}
+
+// Render renders the calendar as an HTML agenda grouped by day, covering the
+// calendar's range or the upcoming days if it has none. Times are shown in
+// the calendar's time zone, or the server's local zone if it has none.
+func Render(cal *models.Calendar, opts Options) ([]byte, error) {
+	loc := time.Local
+	if cal.TimeZone != "" {
+		if zone, err := models.LoadZone(cal.TimeZone); err == nil {
+			loc = zone
+		}
+	}
+
+	now := time.Now().In(loc)
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, loc)
+	from, to := today, today.Add(window)
+	if !cal.From.IsZero() {
+		from, to = cal.From.In(loc), cal.To.In(loc)
+	}
+
+	p := page{
+		Title:        cal.Name,
+		Description:  cal.Description,
+		TimeZone:     loc.String(),
+		SubscribeURL: opts.SubscribeURL,
+		Days:         groupByDay(recurrence.Expand(cal.Events, from, to), from, to, today, loc),
+	}
+
+	var buf bytes.Buffer
+	if err := agendaTemplate.Execute(&buf, p); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// groupByDay sorts events into days, with all-day events first. Events that
+// began before from but are still running are listed on the first day.
+func groupByDay(events []models.Event, from, to, today time.Time, loc *time.Location) []day {
+	type dated struct {
+		event *models.Event
+		day   time.Time
+	}
+
+	var listed []dated
+	for i := range events {
+		event := &events[i]
+		start := dayStart(event, loc)
+		first := time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, loc)
+		end := event.EndTime
+		switch {
+		case end.IsZero():
+			end = event.StartTime
+		case event.AllDay:
+			// All-day ends are exclusive dates
+			end = time.Date(end.Year(), end.Month(), end.Day(), 0, 0, 0, 0, loc).Add(-time.Nanosecond)
+		}
+		if !start.Before(to) || end.Before(from) {
+			continue
+		}
+
+		if start.Before(first) {
+			start = first
+		}
+		listed = append(listed, dated{event, start})
+	}
+
+	sort.SliceStable(listed, func(i, j int) bool {
+		a, b := listed[i], listed[j]
+		if !a.day.Equal(b.day) {
+			return a.day.Before(b.day)
+		}
+		if a.event.AllDay != b.event.AllDay {
+			return a.event.AllDay
+		}
+		return a.event.StartTime.Before(b.event.StartTime)
+	})
+
+	var days []day
+	for _, item := range listed {
+		if len(days) == 0 || days[len(days)-1].Date != item.day.Format("2006-01-02") {
+			days = append(days, day{Label: dayLabel(item.day, today), Date: item.day.Format("2006-01-02")})
+		}
+
+		event := item.event
+		current := &days[len(days)-1]
+		current.Events = append(current.Events, entry{
+			Time:        eventTime(event, loc),
+			Summary:     event.Summary,
+			Description: event.Description,
+			Location:    event.Location,
+			URL:         event.URL,
+			Categories:  event.Categories,
+		})
+	}
+
+	return days
+}
+
+// dayStart returns midnight of the day the event starts on. All-day events
+// are dates and are not shifted between zones.
+func dayStart(event *models.Event, loc *time.Location) time.Time {
+	if event.AllDay {
+		start := event.StartTime
+		return time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, loc)
+	}
+	start := event.StartTime.In(loc)
+	return time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, loc)
+}
+
+func dayLabel(date, today time.Time) string {
+	switch {
+	case date.Equal(today):
+		return "Today"
+	case date.Equal(today.AddDate(0, 0, 1)):
+		return "Tomorrow"
+	default:
+		return date.Format("Monday, January 2")
+	}
+}
+
+func eventTime(event *models.Event, loc *time.Location) string {
+	if event.AllDay {
+		return "All day"
+	}
+	return event.StartTime.In(loc).Format("15:04")
+}
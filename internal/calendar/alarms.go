@@ -0,0 +1,120 @@
+package calendar
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jacobsee/modcal/internal/models"
+)
+
+// AlarmSpec describes a reminder to attach to every event of a calendar or
+// plugin instance. It either triggers a fixed duration before the event or
+// at a time of day relative to the event's date.
+type AlarmSpec struct {
+	Description string
+	Before      time.Duration
+
+	// Time of day trigger, used when AtTimeOfDay is set
+	AtTimeOfDay bool
+	Hour        int
+	Minute      int
+	DaysBefore  int
+}
+
+// NewAlarmSpec builds an alarm from its config representation. at is a
+// 24-hour "HH:MM" time of day and takes precedence over before.
+func NewAlarmSpec(before time.Duration, at string, daysBefore int, description string) (AlarmSpec, error) {
+	spec := AlarmSpec{
+		Description: description,
+		Before:      before,
+		DaysBefore:  daysBefore,
+	}
+
+	if at == "" {
+		if before < 0 {
+			return spec, fmt.Errorf("alarm before must not be negative")
+		}
+		if daysBefore != 0 {
+			return spec, fmt.Errorf("alarm daysBefore requires at")
+		}
+		return spec, nil
+	}
+
+	hour, minute, err := parseTimeOfDay(at)
+	if err != nil {
+		return spec, fmt.Errorf("invalid alarm time %q: %w", at, err)
+	}
+	if daysBefore < 0 {
+		return spec, fmt.Errorf("alarm daysBefore must not be negative")
+	}
+	spec.AtTimeOfDay = true
+	spec.Hour = hour
+	spec.Minute = minute
+
+	return spec, nil
+}
+
+// alarmFor resolves the spec against a specific event
+func (s AlarmSpec) alarmFor(event *models.Event) models.Alarm {
+	alarm := models.Alarm{
+		Description: s.Description,
+		Before:      s.Before,
+	}
+	if s.AtTimeOfDay {
+		// "On the day" means the day in the event's zone, falling back to
+		// the server's zone
+		loc := event.Zone()
+		if loc == nil {
+			loc = time.Local
+		}
+		start := event.StartTime.In(loc)
+		at := time.Date(start.Year(), start.Month(), start.Day()-s.DaysBefore,
+			s.Hour, s.Minute, 0, 0, loc)
+		if event.IsRecurring() {
+			// An absolute trigger would only fit the first occurrence
+			alarm.Before = start.Sub(at)
+		} else {
+			alarm.At = at
+			alarm.Before = 0
+		}
+	}
+	return alarm
+}
+
+// applyAlarms returns events with the given alarms added. Events are copied
+// so cached events are never modified.
+func applyAlarms(events []models.Event, specs []AlarmSpec) []models.Event {
+	if len(specs) == 0 {
+		return events
+	}
+
+	result := make([]models.Event, len(events))
+	for i, event := range events {
+		alarms := make([]models.Alarm, 0, len(event.Alarms)+len(specs))
+		alarms = append(alarms, event.Alarms...)
+		for _, spec := range specs {
+			alarms = append(alarms, spec.alarmFor(&event))
+		}
+		event.Alarms = alarms
+		result[i] = event
+	}
+	return result
+}
+
+func parseTimeOfDay(value string) (int, int, error) {
+	hourStr, minuteStr, ok := strings.Cut(value, ":")
+	if !ok {
+		return 0, 0, fmt.Errorf("expected HH:MM")
+	}
+	hour, err := strconv.Atoi(hourStr)
+	if err != nil || hour < 0 || hour > 23 {
+		return 0, 0, fmt.Errorf("hour must be between 0 and 23")
+	}
+	minute, err := strconv.Atoi(minuteStr)
+	if err != nil || minute < 0 || minute > 59 {
+		return 0, 0, fmt.Errorf("minute must be between 0 and 59")
+	}
+	return hour, minute, nil
+}
@@ -0,0 +1,329 @@
+package calendar
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/jacobsee/modcal/internal/models"
+	"github.com/jacobsee/modcal/internal/recurrence"
+)
+
+const defaultDedupWindow = 2 * time.Hour
+
+// Event fields that can be taken from a specific source when duplicates are
+// merged
+var dedupFields = map[string]bool{
+	"summary":     true,
+	"description": true,
+	"location":    true,
+	"url":         true,
+	"time":        true, // Start, end and time zone
+	"categories":  true, // Merged from all duplicates unless set
+	"show":        true,
+	"alarms":      true,
+}
+
+// DedupOptions configures how duplicate events from different plugin
+// instances are detected and merged
+type DedupOptions struct {
+	Window time.Duration       // Maximum difference between start times
+	Prefer []string            // Plugin IDs in order of preference
+	Fields map[string][]string // Per-field preference, tried before Prefer
+}
+
+// NewDedupOptions builds dedup options from their config representation
+func NewDedupOptions(window time.Duration, prefer []string, fields map[string][]string) (*DedupOptions, error) {
+	if window < 0 {
+		return nil, fmt.Errorf("dedup window must not be negative")
+	}
+	if window == 0 {
+		window = defaultDedupWindow
+	}
+	for field := range fields {
+		if !dedupFields[field] {
+			return nil, fmt.Errorf("unknown dedup field %q", field)
+		}
+	}
+	return &DedupOptions{Window: window, Prefer: prefer, Fields: fields}, nil
+}
+
+// duplicateSet is a group of events from different sources describing the
+// same thing
+type duplicateSet struct {
+	events  []models.Event
+	sources map[string]bool
+}
+
+// deduplicate merges events from different plugin instances that describe
+// the same thing: the same show (by external ID or normalized title) starting
+// within the window of each other. Each set of duplicates becomes one event.
+// Occurrences of recurring series that duplicate a single event are removed
+// from the series and merged into that event. pluginIDs orders sources not
+// listed in the preferences.
+func deduplicate(events []models.Event, opts *DedupOptions, pluginIDs []string) []models.Event {
+	if opts == nil {
+		return events
+	}
+
+	rank := make(map[string]int)
+	for i, id := range pluginIDs {
+		rank[id] = len(opts.Prefer) + i
+	}
+	for i, id := range opts.Prefer {
+		rank[id] = i
+	}
+
+	var singles []models.Event
+	var series []*models.Event
+	var others []models.Event
+	for _, event := range events {
+		switch {
+		case event.IsRecurring() && event.RecurrenceID.IsZero():
+			master := event
+			series = append(series, &master)
+		case event.RecurrenceID.IsZero():
+			singles = append(singles, event)
+		default:
+			others = append(others, event)
+		}
+	}
+
+	sort.SliceStable(singles, func(i, j int) bool {
+		return singles[i].StartTime.Before(singles[j].StartTime)
+	})
+
+	// Events are sorted by start, so candidates for a set are the recent ones
+	var sets []*duplicateSet
+	for _, event := range singles {
+		var match *duplicateSet
+		for i := len(sets) - 1; i >= 0; i-- {
+			set := sets[i]
+			if event.StartTime.Sub(set.events[0].StartTime) > opts.Window {
+				break
+			}
+			if !set.sources[event.Source] && set.matches(&event, opts.Window) {
+				match = set
+				break
+			}
+		}
+		if match == nil {
+			match = &duplicateSet{sources: make(map[string]bool)}
+			sets = append(sets, match)
+		}
+		match.events = append(match.events, event)
+		match.sources[event.Source] = true
+	}
+
+	for _, set := range sets {
+		for _, master := range series {
+			if set.sources[master.Source] {
+				continue
+			}
+			addOccurrence(set, master, opts.Window)
+		}
+	}
+
+	result := make([]models.Event, 0, len(events))
+	for _, set := range sets {
+		result = append(result, mergeDuplicates(set.events, opts, rank))
+	}
+	for _, master := range series {
+		result = append(result, *master)
+	}
+	return append(result, others...)
+}
+
+// matches reports whether the event duplicates any event in the set
+func (s *duplicateSet) matches(event *models.Event, window time.Duration) bool {
+	for i := range s.events {
+		if isDuplicate(&s.events[i], event, window) {
+			return true
+		}
+	}
+	return false
+}
+
+// addOccurrence adds the occurrence of master that duplicates an event of the
+// set, if any, and excludes it from the series. The master's recurrence is
+// copied before it is changed, since it is shared with the event cache.
+func addOccurrence(set *duplicateSet, master *models.Event, window time.Duration) {
+	first := &set.events[0]
+	starts, err := recurrence.Starts(*master, first.StartTime.Add(-window), first.StartTime.Add(window+time.Second))
+	if err != nil {
+		return
+	}
+
+	var duration time.Duration
+	if !master.EndTime.IsZero() {
+		duration = master.EndTime.Sub(master.StartTime)
+	}
+
+	for _, start := range starts {
+		occurrence := *master
+		occurrence.UID = recurrence.InstanceUID(master.UID, start)
+		occurrence.StartTime = start
+		if !master.EndTime.IsZero() {
+			occurrence.EndTime = start.Add(duration)
+		}
+		occurrence.Recurrence = nil
+
+		if !set.matches(&occurrence, window) {
+			continue
+		}
+
+		rec := *master.Recurrence
+		rec.ExDates = append(append([]time.Time(nil), rec.ExDates...), start)
+		master.Recurrence = &rec
+
+		set.events = append(set.events, occurrence)
+		set.sources[master.Source] = true
+		return
+	}
+}
+
+// isDuplicate reports whether two events from different sources describe the
+// same thing. Matching external IDs decide on their own; conflicting ones
+// rule a match out; otherwise the normalized titles have to be equal.
+func isDuplicate(a, b *models.Event, window time.Duration) bool {
+	if a.Source == b.Source || a.AllDay != b.AllDay {
+		return false
+	}
+	diff := a.StartTime.Sub(b.StartTime)
+	if diff < -window || diff > window {
+		return false
+	}
+
+	conflict := false
+	for key, id := range a.ExternalIDs {
+		if other, ok := b.ExternalIDs[key]; ok {
+			if other == id {
+				return true
+			}
+			conflict = true
+		}
+	}
+	if conflict {
+		return false
+	}
+
+	title := normalizeTitle(eventTitle(a))
+	return title != "" && title == normalizeTitle(eventTitle(b))
+}
+
+// eventTitle is what an event is about: its show, or else its summary
+func eventTitle(event *models.Event) string {
+	if event.Show != "" {
+		return event.Show
+	}
+	return event.Summary
+}
+
+// normalizeTitle lowercases a title and reduces it to letters and digits
+// separated by single spaces
+func normalizeTitle(title string) string {
+	var b strings.Builder
+	space := false
+	for _, r := range strings.ToLower(title) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if space && b.Len() > 0 {
+				b.WriteByte(' ')
+			}
+			b.WriteRune(r)
+			space = false
+		} else {
+			space = true
+		}
+	}
+	return b.String()
+}
+
+// mergeDuplicates combines a set of duplicates into one event. The most
+// preferred source provides the identity of the event, and each field comes
+// from the most preferred source that has a value for it.
+func mergeDuplicates(events []models.Event, opts *DedupOptions, rank map[string]int) models.Event {
+	if len(events) == 1 {
+		return events[0]
+	}
+
+	byPreference := func(field string) []*models.Event {
+		fieldRank := make(map[string]int)
+		for i, id := range opts.Fields[field] {
+			fieldRank[id] = i + 1
+		}
+		ordered := make([]*models.Event, len(events))
+		for i := range events {
+			ordered[i] = &events[i]
+		}
+		sort.SliceStable(ordered, func(i, j int) bool {
+			ri, rj := fieldRank[ordered[i].Source], fieldRank[ordered[j].Source]
+			if (ri > 0) != (rj > 0) {
+				return ri > 0
+			}
+			if ri != rj {
+				return ri < rj
+			}
+			return sourceRank(rank, ordered[i].Source) < sourceRank(rank, ordered[j].Source)
+		})
+		return ordered
+	}
+	pick := func(field string, has func(*models.Event) bool) *models.Event {
+		ordered := byPreference(field)
+		for _, event := range ordered {
+			if has(event) {
+				return event
+			}
+		}
+		return ordered[0]
+	}
+
+	merged := *byPreference("")[0]
+
+	merged.Summary = pick("summary", func(e *models.Event) bool { return e.Summary != "" }).Summary
+	merged.Description = pick("description", func(e *models.Event) bool { return e.Description != "" }).Description
+	merged.Location = pick("location", func(e *models.Event) bool { return e.Location != "" }).Location
+	merged.URL = pick("url", func(e *models.Event) bool { return e.URL != "" }).URL
+	merged.Show = pick("show", func(e *models.Event) bool { return e.Show != "" }).Show
+	merged.Alarms = pick("alarms", func(e *models.Event) bool { return len(e.Alarms) > 0 }).Alarms
+
+	timed := pick("time", func(e *models.Event) bool { return true })
+	merged.StartTime = timed.StartTime
+	merged.EndTime = timed.EndTime
+	merged.TimeZone = timed.TimeZone
+
+	if _, ok := opts.Fields["categories"]; ok {
+		merged.Categories = pick("categories", func(e *models.Event) bool { return len(e.Categories) > 0 }).Categories
+	} else {
+		merged.Categories = nil
+		seen := make(map[string]bool)
+		for _, event := range byPreference("") {
+			for _, category := range event.Categories {
+				if !seen[strings.ToLower(category)] {
+					seen[strings.ToLower(category)] = true
+					merged.Categories = append(merged.Categories, category)
+				}
+			}
+		}
+	}
+
+	merged.ExternalIDs = make(map[string]string)
+	for _, event := range byPreference("") {
+		for key, id := range event.ExternalIDs {
+			if _, ok := merged.ExternalIDs[key]; !ok {
+				merged.ExternalIDs[key] = id
+			}
+		}
+	}
+
+	return merged
+}
+
+// sourceRank orders sources by preference; unknown sources come last
+func sourceRank(rank map[string]int, source string) int {
+	if r, ok := rank[source]; ok {
+		return r
+	}
+	return len(rank)
+}
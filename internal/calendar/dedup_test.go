@@ -0,0 +1,179 @@
+package calendar
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/jacobsee/modcal/internal/models"
+)
+
+func TestDeduplicate(t *testing.T) {
+	start := time.Date(2024, 3, 5, 20, 0, 0, 0, time.UTC)
+	trakt := models.Event{
+		UID: "trakt-1", Source: "trakt", Summary: "Severance - S2E1", Show: "Severance",
+		StartTime: start, EndTime: start.Add(time.Hour),
+		Categories:  []string{"tv", "Trakt"},
+		ExternalIDs: map[string]string{"tvdb": "371980"},
+	}
+	sonarr := models.Event{
+		UID: "sonarr-1", Source: "sonarr", Summary: "Severance (S02E01)", Show: "severance",
+		Description: "Mark leads a team.", Location: "Apple TV+",
+		StartTime: start.Add(30 * time.Minute), EndTime: start.Add(90 * time.Minute),
+		Categories:  []string{"TV", "sonarr"},
+		ExternalIDs: map[string]string{"tvdb": "371980", "imdb": "tt11280740"},
+	}
+	other := models.Event{
+		UID: "sonarr-2", Source: "sonarr", Summary: "Other", Show: "Other",
+		StartTime: start,
+	}
+
+	tests := []struct {
+		name   string
+		events []models.Event
+		opts   *DedupOptions
+		check  func(t *testing.T, got []models.Event)
+	}{
+		{
+			name:   "merged by preference",
+			events: []models.Event{sonarr, trakt, other},
+			opts:   &DedupOptions{Window: 2 * time.Hour, Prefer: []string{"trakt"}},
+			check: func(t *testing.T, got []models.Event) {
+				if len(got) != 2 {
+					t.Fatalf("got %d events, want 2", len(got))
+				}
+				merged := find(t, got, "trakt-1")
+				if merged.Summary != trakt.Summary || !merged.StartTime.Equal(trakt.StartTime) {
+					t.Errorf("summary and time from %s, want trakt", merged.Summary)
+				}
+				// Fields trakt has no value for come from sonarr
+				if merged.Description != sonarr.Description || merged.Location != sonarr.Location {
+					t.Errorf("description %q, location %q", merged.Description, merged.Location)
+				}
+				if want := []string{"tv", "Trakt", "sonarr"}; !reflect.DeepEqual(merged.Categories, want) {
+					t.Errorf("categories = %q, want %q", merged.Categories, want)
+				}
+				if want := map[string]string{"tvdb": "371980", "imdb": "tt11280740"}; !reflect.DeepEqual(merged.ExternalIDs, want) {
+					t.Errorf("external IDs = %v, want %v", merged.ExternalIDs, want)
+				}
+			},
+		},
+		{
+			name:   "per-field preference",
+			events: []models.Event{sonarr, trakt},
+			opts: &DedupOptions{Window: 2 * time.Hour, Prefer: []string{"trakt"}, Fields: map[string][]string{
+				"time":       {"sonarr"},
+				"categories": {"sonarr"},
+			}},
+			check: func(t *testing.T, got []models.Event) {
+				merged := find(t, got, "trakt-1")
+				if !merged.StartTime.Equal(sonarr.StartTime) || !merged.EndTime.Equal(sonarr.EndTime) {
+					t.Errorf("time = %v-%v, want sonarr's", merged.StartTime, merged.EndTime)
+				}
+				if !reflect.DeepEqual(merged.Categories, sonarr.Categories) {
+					t.Errorf("categories = %q, want sonarr's", merged.Categories)
+				}
+				if merged.Summary != trakt.Summary {
+					t.Errorf("summary = %q, want trakt's", merged.Summary)
+				}
+			},
+		},
+		{
+			name:   "outside the window",
+			events: []models.Event{trakt, sonarr},
+			opts:   &DedupOptions{Window: 10 * time.Minute},
+			check:  wantUIDs("sonarr-1", "trakt-1"),
+		},
+		{
+			name: "conflicting external IDs",
+			events: []models.Event{trakt, func() models.Event {
+				e := sonarr
+				e.ExternalIDs = map[string]string{"tvdb": "1"}
+				return e
+			}()},
+			opts:  &DedupOptions{Window: 2 * time.Hour},
+			check: wantUIDs("sonarr-1", "trakt-1"),
+		},
+		{
+			name: "matching titles without external IDs",
+			events: []models.Event{
+				{UID: "a", Source: "ics", Summary: "Doctor Who!", StartTime: start},
+				{UID: "b", Source: "tvmaze", Summary: "doctor  who", StartTime: start.Add(time.Hour)},
+			},
+			opts:  &DedupOptions{Window: 2 * time.Hour, Prefer: []string{"tvmaze"}},
+			check: wantUIDs("b"),
+		},
+		{
+			name: "same source",
+			events: []models.Event{
+				{UID: "a", Source: "ics", Summary: "News", StartTime: start},
+				{UID: "b", Source: "ics", Summary: "News", StartTime: start.Add(time.Hour)},
+			},
+			opts:  &DedupOptions{Window: 2 * time.Hour},
+			check: wantUIDs("a", "b"),
+		},
+		{
+			name: "occurrence of a series",
+			events: []models.Event{
+				{
+					UID: "series", Source: "mal", Show: "Frieren", StartTime: start.AddDate(0, 0, -7),
+					Recurrence: &models.Recurrence{Rule: "FREQ=WEEKLY"},
+				},
+				{UID: "single", Source: "anilist", Show: "Frieren", StartTime: start.Add(15 * time.Minute)},
+			},
+			opts: &DedupOptions{Window: 2 * time.Hour, Prefer: []string{"anilist"}},
+			check: func(t *testing.T, got []models.Event) {
+				wantUIDs("series", "single")(t, got)
+				series := find(t, got, "series")
+				if exdates := series.Recurrence.ExDates; len(exdates) != 1 || !exdates[0].Equal(start) {
+					t.Errorf("series EXDATE = %v, want %v", exdates, start)
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.check(t, deduplicate(tt.events, tt.opts, []string{"sonarr", "trakt"}))
+		})
+	}
+}
+
+func TestNewDedupOptions(t *testing.T) {
+	opts, err := NewDedupOptions(0, nil, nil)
+	if err != nil || opts.Window != defaultDedupWindow {
+		t.Errorf("NewDedupOptions(0) = %+v, %v, want the default window", opts, err)
+	}
+	if _, err := NewDedupOptions(-time.Minute, nil, nil); err == nil {
+		t.Error("negative window accepted")
+	}
+	if _, err := NewDedupOptions(0, nil, map[string][]string{"title": {"trakt"}}); err == nil {
+		t.Error("unknown field accepted")
+	}
+}
+
+func find(t *testing.T, events []models.Event, uid string) models.Event {
+	t.Helper()
+	for _, event := range events {
+		if event.UID == uid {
+			return event
+		}
+	}
+	t.Fatalf("no event %q in %+v", uid, events)
+	return models.Event{}
+}
+
+func wantUIDs(uids ...string) func(t *testing.T, got []models.Event) {
+	return func(t *testing.T, got []models.Event) {
+		t.Helper()
+		var have []string
+		for _, event := range got {
+			have = append(have, event.UID)
+		}
+		sort.Strings(have)
+		if !reflect.DeepEqual(have, uids) {
+			t.Errorf("UIDs = %q, want %q", have, uids)
+		}
+	}
+}
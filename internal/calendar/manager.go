@@ -2,11 +2,16 @@ package calendar
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"log/slog"
 	"sync"
+	"time"
 
+	"github.com/jacobsee/modcal/internal/logging"
 	"github.com/jacobsee/modcal/internal/models"
 	"github.com/jacobsee/modcal/internal/plugin"
+	"github.com/jacobsee/modcal/internal/store"
 )
 
 // Manager handles calendar aggregation and event fetching
@@ -15,6 +20,12 @@ type Manager struct {
 	calendars     map[string]*CalendarDefinition
 	eventCache    map[string][]models.Event
 	pluginManager *PluginManager
+	stamps        *stampTracker
+	store         store.Store
+	logger        *slog.Logger
+
+	statusMu sync.Mutex
+	status   map[string]*PluginStatus
 }
 
 // CalendarDefinition defines a calendar with its associated plugins
@@ -22,26 +33,61 @@ type CalendarDefinition struct {
 	Name        string
 	Description string
 	PluginIDs   []string
+	Alarms      []AlarmSpec
+	TimeZone    string        // IANA zone events are displayed in, if set
+	Rules       []Rule        // Filters and transformations, applied in order
+	Dedup       *DedupOptions // Merges duplicates across plugins if set
+
+	// Spoilers overrides the spoiler-free settings of the plugin instances
+	Spoilers *SpoilerOptions
+}
+
+// location returns the zone the calendar is displayed in: its time zone, or
+// the server's local zone
+func (d *CalendarDefinition) location() *time.Location {
+	if d.TimeZone != "" {
+		if loc, err := models.LoadZone(d.TimeZone); err == nil {
+			return loc
+		}
+	}
+	return time.Local
+}
+
+// InstanceOptions holds per-instance settings the manager applies to a
+// plugin's events
+type InstanceOptions struct {
+	Alarms   []AlarmSpec
+	Spoilers *SpoilerOptions
 }
 
 // PluginManager manages plugin instances
 type PluginManager struct {
 	mu        sync.RWMutex
 	instances map[string]plugin.Plugin
+	options   map[string]InstanceOptions
 }
 
 // NewPluginManager creates a new plugin manager
 func NewPluginManager() *PluginManager {
 	return &PluginManager{
 		instances: make(map[string]plugin.Plugin),
+		options:   make(map[string]InstanceOptions),
 	}
 }
 
 // AddInstance adds a plugin instance with a specific ID
-func (pm *PluginManager) AddInstance(id string, p plugin.Plugin) {
+func (pm *PluginManager) AddInstance(id string, p plugin.Plugin, opts InstanceOptions) {
 	pm.mu.Lock()
 	defer pm.mu.Unlock()
 	pm.instances[id] = p
+	pm.options[id] = opts
+}
+
+// instanceOptions returns the settings of a plugin instance
+func (pm *PluginManager) instanceOptions(id string) InstanceOptions {
+	pm.mu.RLock()
+	defer pm.mu.RUnlock()
+	return pm.options[id]
 }
 
 // GetInstance retrieves a plugin instance by ID
@@ -52,12 +98,22 @@ func (pm *PluginManager) GetInstance(id string) (plugin.Plugin, bool) {
 	return p, ok
 }
 
-// NewManager creates a new calendar manager
-func NewManager(pm *PluginManager) *Manager {
+// Plugin returns the plugin instance with the given ID
+func (m *Manager) Plugin(id string) (plugin.Plugin, bool) {
+	return m.pluginManager.GetInstance(id)
+}
+
+// NewManager creates a new calendar manager that persists fetched events
+// and their revision stamps in st
+func NewManager(pm *PluginManager, st store.Store, logger *slog.Logger) *Manager {
 	return &Manager{
 		calendars:     make(map[string]*CalendarDefinition),
 		eventCache:    make(map[string][]models.Event),
 		pluginManager: pm,
+		stamps:        newStampTracker(st, logger),
+		store:         st,
+		logger:        logger,
+		status:        make(map[string]*PluginStatus),
 	}
 }
 
@@ -75,7 +131,7 @@ func (m *Manager) GetCalendar(name string) (*models.Calendar, error) {
 
 	calDef, exists := m.calendars[name]
 	if !exists {
-		return nil, fmt.Errorf("calendar %s not found", name)
+		return nil, errCalendarNotFound(name)
 	}
 
 	var allEvents []models.Event
@@ -85,21 +141,74 @@ func (m *Manager) GetCalendar(name string) (*models.Calendar, error) {
 		}
 	}
 
+	now := time.Now()
+	allEvents = applyTimeZone(allEvents, calDef.TimeZone)
+	allEvents = m.applySpoilers(allEvents, calDef.Spoilers, now)
+	allEvents = deduplicate(allEvents, calDef.Dedup, calDef.PluginIDs)
+	allEvents = applyRules(allEvents, calDef.Rules, calDef.location())
+	allEvents = applyAlarms(allEvents, calDef.Alarms)
+	m.stamps.apply(calDef.Name, allEvents, now)
+
 	return &models.Calendar{
 		Name:        calDef.Name,
 		Description: calDef.Description,
+		TimeZone:    calDef.TimeZone,
 		Events:      allEvents,
 	}, nil
 }
 
-// RefreshEvents fetches events from all plugins
-func (m *Manager) RefreshEvents(ctx context.Context) error {
-	m.pluginManager.mu.RLock()
-	instances := make(map[string]plugin.Plugin, len(m.pluginManager.instances))
-	for id, p := range m.pluginManager.instances {
+// LoadEvents fills the cache with the events the store saved for each
+// plugin instance, so calendars can be served before the first refresh. It
+// returns the number of instances that had saved events.
+func (m *Manager) LoadEvents() (int, error) {
+	instances := m.pluginManager.snapshot()
+
+	loaded := 0
+	var errs []error
+	for id, p := range instances {
+		snapshot, ok, err := m.store.Load(id)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("plugin %s: %w", id, err))
+			continue
+		}
+		if !ok {
+			continue
+		}
+
+		logging.ForPlugin(m.logger, id, p.Name()).Info("Loaded saved events",
+			"events", len(snapshot.Events), "fetched_at", snapshot.FetchedAt)
+		events := applyAlarms(snapshot.Events, m.pluginManager.instanceOptions(id).Alarms)
+
+		m.mu.Lock()
+		m.eventCache[id] = events
+		m.mu.Unlock()
+
+		m.statusMu.Lock()
+		status := m.pluginStatus(id, p.Name())
+		status.LastSuccess = snapshot.FetchedAt
+		status.EventCount = len(snapshot.Events)
+		m.statusMu.Unlock()
+		loaded++
+	}
+
+	return loaded, errors.Join(errs...)
+}
+
+// snapshot returns a copy of the plugin instances by ID
+func (pm *PluginManager) snapshot() map[string]plugin.Plugin {
+	pm.mu.RLock()
+	defer pm.mu.RUnlock()
+
+	instances := make(map[string]plugin.Plugin, len(pm.instances))
+	for id, p := range pm.instances {
 		instances[id] = p
 	}
-	m.pluginManager.mu.RUnlock()
+	return instances
+}
+
+// RefreshEvents fetches events from all plugins
+func (m *Manager) RefreshEvents(ctx context.Context) error {
+	instances := m.pluginManager.snapshot()
 
 	var wg sync.WaitGroup
 	errChan := make(chan error, len(instances))
@@ -108,16 +217,9 @@ func (m *Manager) RefreshEvents(ctx context.Context) error {
 		wg.Add(1)
 		go func(pluginID string, plug plugin.Plugin) {
 			defer wg.Done()
-
-			events, err := plug.FetchEvents(ctx)
-			if err != nil {
-				errChan <- fmt.Errorf("plugin %s: %w", pluginID, err)
-				return
+			if err := m.refreshPlugin(ctx, pluginID, plug); err != nil {
+				errChan <- err
 			}
-
-			m.mu.Lock()
-			m.eventCache[pluginID] = events
-			m.mu.Unlock()
 		}(id, p)
 	}
 
@@ -129,11 +231,53 @@ func (m *Manager) RefreshEvents(ctx context.Context) error {
 		errs = append(errs, err)
 	}
 
-	if len(errs) > 0 {
-		return errs[0]
+	return errors.Join(errs...)
+}
+
+// RefreshPlugin fetches the events of one plugin instance
+func (m *Manager) RefreshPlugin(ctx context.Context, pluginID string) error {
+	plug, ok := m.pluginManager.GetInstance(pluginID)
+	if !ok {
+		return fmt.Errorf("plugin %s not found", pluginID)
+	}
+	return m.refreshPlugin(ctx, pluginID, plug)
+}
+
+func (m *Manager) refreshPlugin(ctx context.Context, pluginID string, plug plugin.Plugin) error {
+	logger := logging.ForPlugin(m.logger, pluginID, plug.Name())
+	logger.Debug("Refreshing events")
+
+	start := time.Now()
+	events, err := plug.FetchEvents(ctx)
+	m.recordRefresh(pluginID, plug.Name(), start, len(events), err)
+	if err != nil {
+		logger.Warn("Failed to refresh events", "error", err, "duration", time.Since(start))
+		return fmt.Errorf("plugin %s: %w", pluginID, err)
+	}
+	logger.Info("Refreshed events", "events", len(events), "duration", time.Since(start))
+
+	for i := range events {
+		events[i].Source = pluginID
 	}
 
-	return nil
+	// Events are saved without alarms, which are applied again on load in
+	// case the config changed
+	var saveErr error
+	if err := m.store.Save(pluginID, store.Snapshot{Events: events, FetchedAt: time.Now()}); err != nil {
+		logger.Error("Failed to save events", "error", err)
+		saveErr = fmt.Errorf("plugin %s: failed to save events: %w", pluginID, err)
+	}
+	events = applyAlarms(events, m.pluginManager.instanceOptions(pluginID).Alarms)
+
+	m.mu.Lock()
+	m.eventCache[pluginID] = events
+	m.mu.Unlock()
+
+	return saveErr
+}
+
+func errCalendarNotFound(name string) error {
+	return fmt.Errorf("calendar %s not found", name)
 }
 
 // ListCalendars returns all calendar names
@@ -0,0 +1,109 @@
+package calendar
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"net"
+	"net/http"
+
+	"github.com/jacobsee/modcal/internal/metrics"
+	"github.com/jacobsee/modcal/internal/plugin"
+	"github.com/jacobsee/modcal/internal/tokens"
+)
+
+var (
+	refreshDuration = metrics.Default.NewHistogramVec(
+		"modcal_refresh_duration_seconds",
+		"Time taken to fetch the events of a plugin instance.",
+		metrics.DefaultBuckets,
+		"plugin", "type",
+	)
+	refreshErrors = metrics.Default.NewCounterVec(
+		"modcal_refresh_errors_total",
+		"Failed refreshes of a plugin instance, by error class.",
+		"plugin", "type", "class",
+	)
+)
+
+// RegisterMetrics adds gauges of the manager's cached events to r
+func (m *Manager) RegisterMetrics(r *metrics.Registry) {
+	r.NewGaugeFunc(
+		"modcal_cached_events",
+		"Events currently cached for a plugin instance.",
+		m.cachedEventCounts,
+		"plugin",
+	)
+	r.NewGaugeFunc(
+		"modcal_calendar_cached_events",
+		"Cached events of the plugin instances of a calendar, before deduplication and rules.",
+		m.calendarEventCounts,
+		"calendar",
+	)
+}
+
+func (m *Manager) cachedEventCounts() map[string]float64 {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	counts := make(map[string]float64, len(m.eventCache))
+	for id, events := range m.eventCache {
+		counts[metrics.LabelKey(id)] = float64(len(events))
+	}
+	return counts
+}
+
+func (m *Manager) calendarEventCounts() map[string]float64 {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	counts := make(map[string]float64, len(m.calendars))
+	for name, calDef := range m.calendars {
+		count := 0
+		for _, id := range calDef.PluginIDs {
+			count += len(m.eventCache[id])
+		}
+		counts[metrics.LabelKey(name)] = float64(count)
+	}
+	return counts
+}
+
+// errorClass groups refresh errors for metrics
+func errorClass(err error) string {
+	var httpErr *plugin.HTTPError
+	var pathErr *fs.PathError
+	var netErr net.Error
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+
+	switch {
+	case errors.Is(err, tokens.ErrNoToken):
+		return "auth"
+	case errors.As(err, &httpErr):
+		switch {
+		case httpErr.StatusCode == http.StatusUnauthorized || httpErr.StatusCode == http.StatusForbidden:
+			return "auth"
+		case httpErr.StatusCode == http.StatusTooManyRequests:
+			return "rate_limit"
+		case httpErr.StatusCode >= 500:
+			return "server"
+		default:
+			return "client"
+		}
+	case errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled):
+		return "timeout"
+	case errors.As(err, &pathErr):
+		// Checked before network errors, which syscall errors also satisfy
+		return "file"
+	case errors.As(err, &netErr):
+		if netErr.Timeout() {
+			return "timeout"
+		}
+		return "network"
+	case errors.As(err, &syntaxErr) || errors.As(err, &typeErr):
+		return "decode"
+	default:
+		return "other"
+	}
+}
@@ -0,0 +1,116 @@
+package calendar
+
+import (
+	"strings"
+	"time"
+
+	"github.com/jacobsee/modcal/internal/models"
+	"github.com/jacobsee/modcal/internal/recurrence"
+)
+
+// Recurring series are expanded at most this far past a one-sided range
+const openRangeSpan = 365 * 24 * time.Hour
+
+// Query narrows down the events of a calendar. Zero fields match everything.
+type Query struct {
+	From              time.Time // Events ending before From are dropped
+	To                time.Time // Events starting at or after To are dropped
+	Categories        []string  // Keep events with any of these categories
+	ExcludeCategories []string  // Drop events with any of these categories
+	Text              string    // Keep events whose summary or description contains this
+}
+
+// HasRange reports whether the query limits events to a time range
+func (q Query) HasRange() bool {
+	return !q.From.IsZero() || !q.To.IsZero()
+}
+
+// QueryCalendar retrieves a calendar with only the events matching q. If q
+// has a time range, recurring series are expanded into their occurrences
+// within it.
+func (m *Manager) QueryCalendar(name string, q Query) (*models.Calendar, error) {
+	cal, err := m.GetCalendar(name)
+	if err != nil {
+		return nil, err
+	}
+
+	events := cal.Events
+	if q.HasRange() {
+		from, to := q.From, q.To
+		if from.IsZero() {
+			from = to.Add(-openRangeSpan)
+		}
+		if to.IsZero() {
+			to = from.Add(openRangeSpan)
+		}
+		cal.From, cal.To = from, to
+		events = recurrence.Expand(events, from, to)
+	}
+
+	filtered := make([]models.Event, 0, len(events))
+	for _, event := range events {
+		if q.matches(&event) {
+			filtered = append(filtered, event)
+		}
+	}
+	cal.Events = filtered
+
+	return cal, nil
+}
+
+// Location returns the zone a calendar is displayed in: its configured time
+// zone, or the server's local zone
+func (m *Manager) Location(name string) (*time.Location, error) {
+	m.mu.RLock()
+	calDef, exists := m.calendars[name]
+	m.mu.RUnlock()
+
+	if !exists {
+		return nil, errCalendarNotFound(name)
+	}
+	return calDef.location(), nil
+}
+
+func (q Query) matches(event *models.Event) bool {
+	if !q.To.IsZero() && !event.StartTime.Before(q.To) {
+		return false
+	}
+	if !q.From.IsZero() {
+		end := event.EndTime
+		if end.IsZero() {
+			end = event.StartTime
+		}
+		// Events without duration that start exactly at From are kept
+		if end.Before(q.From) || (end.Equal(q.From) && end.After(event.StartTime)) {
+			return false
+		}
+	}
+
+	if len(q.Categories) > 0 && !hasAnyCategory(event, q.Categories) {
+		return false
+	}
+	if hasAnyCategory(event, q.ExcludeCategories) {
+		return false
+	}
+
+	if q.Text != "" {
+		text := strings.ToLower(q.Text)
+		if !strings.Contains(strings.ToLower(event.Summary), text) &&
+			!strings.Contains(strings.ToLower(event.Description), text) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func hasAnyCategory(event *models.Event, categories []string) bool {
+	for _, want := range categories {
+		for _, category := range event.Categories {
+			if strings.EqualFold(category, want) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,221 @@
+package calendar
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jacobsee/modcal/internal/models"
+)
+
+// Rule filters or transforms the events of a calendar. Include keeps only
+// matching events and Exclude drops matching events. The transformations
+// apply to every remaining event, or only to those matching When if set.
+type Rule struct {
+	Include *Match
+	Exclude *Match
+	When    *Match
+
+	Rewrites        []Rewrite
+	Prefix          string
+	RemapCategories map[string]string // See NewRemap
+}
+
+// Match is a condition on events. All of its set fields have to match.
+type Match struct {
+	Categories []string       // Any of these categories
+	Summary    *regexp.Regexp // Summary matches this expression
+	Shows      []string       // Any of these shows, compared case-insensitively
+	Window     *TimeWindow    // Start time falls within this time of day
+}
+
+// Rewrite replaces matches of Pattern in event summaries. Replace may refer
+// to capture groups as in regexp.Expand, e.g. "${1}".
+type Rewrite struct {
+	Pattern *regexp.Regexp
+	Replace string
+}
+
+// TimeWindow is a range of the day in minutes since midnight. A window that
+// ends before it starts wraps around midnight.
+type TimeWindow struct {
+	Start int
+	End   int
+}
+
+// NewMatch builds a match from its config representation. summary is a
+// regular expression and between a "HH:MM-HH:MM" time of day range.
+func NewMatch(categories []string, summary string, shows []string, between string) (*Match, error) {
+	match := &Match{
+		Categories: categories,
+		Shows:      shows,
+	}
+
+	if summary != "" {
+		re, err := regexp.Compile(summary)
+		if err != nil {
+			return nil, fmt.Errorf("invalid summary pattern: %w", err)
+		}
+		match.Summary = re
+	}
+
+	if between != "" {
+		window, err := parseTimeWindow(between)
+		if err != nil {
+			return nil, fmt.Errorf("invalid time window %q: %w", between, err)
+		}
+		match.Window = window
+	}
+
+	return match, nil
+}
+
+// NewRewrite builds a summary rewrite from a regular expression
+func NewRewrite(pattern, replace string) (Rewrite, error) {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return Rewrite{}, fmt.Errorf("invalid rewrite pattern: %w", err)
+	}
+	return Rewrite{Pattern: re, Replace: replace}, nil
+}
+
+// NewRemap builds a category remapping from old categories to new ones,
+// where "" removes a category. Categories are compared case-insensitively,
+// so old categories that only differ in case are rejected.
+func NewRemap(categories map[string]string) (map[string]string, error) {
+	from := make([]string, 0, len(categories))
+	for category := range categories {
+		from = append(from, category)
+	}
+	sort.Strings(from)
+
+	remap := make(map[string]string, len(categories))
+	seen := make(map[string]string, len(categories))
+	for _, category := range from {
+		key := strings.ToLower(category)
+		if other, ok := seen[key]; ok {
+			return nil, fmt.Errorf("categories %q and %q only differ in case", other, category)
+		}
+		seen[key] = category
+		remap[key] = categories[category]
+	}
+	return remap, nil
+}
+
+func parseTimeWindow(value string) (*TimeWindow, error) {
+	from, to, ok := strings.Cut(value, "-")
+	if !ok {
+		return nil, fmt.Errorf("expected HH:MM-HH:MM")
+	}
+
+	startHour, startMinute, err := parseTimeOfDay(strings.TrimSpace(from))
+	if err != nil {
+		return nil, err
+	}
+	endHour, endMinute, err := parseTimeOfDay(strings.TrimSpace(to))
+	if err != nil {
+		return nil, err
+	}
+
+	return &TimeWindow{
+		Start: startHour*60 + startMinute,
+		End:   endHour*60 + endMinute,
+	}, nil
+}
+
+// contains reports whether the time of day of t falls within the window
+func (w *TimeWindow) contains(t time.Time) bool {
+	minute := t.Hour()*60 + t.Minute()
+	if w.Start <= w.End {
+		return minute >= w.Start && minute < w.End
+	}
+	return minute >= w.Start || minute < w.End
+}
+
+// matches checks the event against the condition. Times of day are taken in
+// loc; all-day events have no time of day and always match a window.
+func (m *Match) matches(event *models.Event, loc *time.Location) bool {
+	if len(m.Categories) > 0 && !hasAnyCategory(event, m.Categories) {
+		return false
+	}
+	if m.Summary != nil && !m.Summary.MatchString(event.Summary) {
+		return false
+	}
+	if len(m.Shows) > 0 {
+		found := false
+		for _, show := range m.Shows {
+			if event.Show != "" && strings.EqualFold(event.Show, show) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if m.Window != nil && !event.AllDay && !m.Window.contains(event.StartTime.In(loc)) {
+		return false
+	}
+	return true
+}
+
+// applyRules runs the rules over the events in order. Events are copied
+// before they are changed, since they are shared with the event cache.
+func applyRules(events []models.Event, rules []Rule, loc *time.Location) []models.Event {
+	if len(rules) == 0 {
+		return events
+	}
+
+	result := make([]models.Event, 0, len(events))
+	for _, event := range events {
+		if keep := applyRulesTo(&event, rules, loc); keep {
+			result = append(result, event)
+		}
+	}
+	return result
+}
+
+// applyRulesTo applies the rules to one event, returning false if it is
+// filtered out
+func applyRulesTo(event *models.Event, rules []Rule, loc *time.Location) bool {
+	for _, rule := range rules {
+		if rule.Include != nil && !rule.Include.matches(event, loc) {
+			return false
+		}
+		if rule.Exclude != nil && rule.Exclude.matches(event, loc) {
+			return false
+		}
+		if rule.When != nil && !rule.When.matches(event, loc) {
+			continue
+		}
+
+		for _, rewrite := range rule.Rewrites {
+			event.Summary = rewrite.Pattern.ReplaceAllString(event.Summary, rewrite.Replace)
+		}
+		if rule.Prefix != "" {
+			event.Summary = rule.Prefix + event.Summary
+		}
+		if len(rule.RemapCategories) > 0 {
+			event.Categories = remapCategories(event.Categories, rule.RemapCategories)
+		}
+	}
+	return true
+}
+
+func remapCategories(categories []string, remap map[string]string) []string {
+	result := make([]string, 0, len(categories))
+	seen := make(map[string]bool, len(categories))
+	for _, category := range categories {
+		if to, ok := remap[strings.ToLower(category)]; ok {
+			category = to
+		}
+		if category == "" || seen[strings.ToLower(category)] {
+			continue
+		}
+		seen[strings.ToLower(category)] = true
+		result = append(result, category)
+	}
+	return result
+}
@@ -0,0 +1,116 @@
+package calendar
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/jacobsee/modcal/internal/models"
+)
+
+func mustMatch(t *testing.T, categories []string, summary string, shows []string, between string) *Match {
+	t.Helper()
+	match, err := NewMatch(categories, summary, shows, between)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return match
+}
+
+func TestApplyRules(t *testing.T) {
+	evening := time.Date(2024, 3, 5, 21, 0, 0, 0, time.UTC)
+	morning := time.Date(2024, 3, 5, 7, 0, 0, 0, time.UTC)
+	events := []models.Event{
+		{UID: "news", Summary: "Morning News", Categories: []string{"tv"}, StartTime: morning},
+		{UID: "anime", Summary: "Frieren - Episode 5", Show: "Frieren", Categories: []string{"Anime", "mal"}, StartTime: evening},
+		{UID: "movie", Summary: "Dune", Categories: []string{"movie"}, StartTime: evening, AllDay: true},
+	}
+	rewrite, err := NewRewrite(`^(.*) - Episode (\d+)$`, "${1} #${2}")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		rules     []Rule
+		summaries map[string]string // Remaining events by UID
+	}{
+		{
+			name:      "include categories",
+			rules:     []Rule{{Include: mustMatch(t, []string{"ANIME", "movie"}, "", nil, "")}},
+			summaries: map[string]string{"anime": "Frieren - Episode 5", "movie": "Dune"},
+		},
+		{
+			name:      "exclude summary",
+			rules:     []Rule{{Exclude: mustMatch(t, nil, "(?i)news", nil, "")}},
+			summaries: map[string]string{"anime": "Frieren - Episode 5", "movie": "Dune"},
+		},
+		{
+			// All-day events have no time of day and always match
+			name:      "time window",
+			rules:     []Rule{{Include: mustMatch(t, nil, "", nil, "18:00-02:00")}},
+			summaries: map[string]string{"anime": "Frieren - Episode 5", "movie": "Dune"},
+		},
+		{
+			name: "conditional rewrite and prefix",
+			rules: []Rule{{
+				When:     mustMatch(t, nil, "", []string{"frieren"}, ""),
+				Rewrites: []Rewrite{rewrite},
+				Prefix:   "[Anime] ",
+			}},
+			summaries: map[string]string{"news": "Morning News", "anime": "[Anime] Frieren #5", "movie": "Dune"},
+		},
+		{
+			name: "rules apply in order",
+			rules: []Rule{
+				{Prefix: "TV: "},
+				{Exclude: mustMatch(t, nil, "^TV: Dune$", nil, "")},
+			},
+			summaries: map[string]string{"news": "TV: Morning News", "anime": "TV: Frieren - Episode 5"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := applyRules(events, tt.rules, time.UTC)
+			summaries := make(map[string]string)
+			for _, event := range got {
+				summaries[event.UID] = event.Summary
+			}
+			if !reflect.DeepEqual(summaries, tt.summaries) {
+				t.Errorf("got %v, want %v", summaries, tt.summaries)
+			}
+		})
+	}
+
+	// The events passed in are shared with the cache and must not change
+	if events[1].Summary != "Frieren - Episode 5" {
+		t.Errorf("applyRules changed its input: %q", events[1].Summary)
+	}
+}
+
+func TestRemapCategories(t *testing.T) {
+	remap, err := NewRemap(map[string]string{"ANIME": "tv", "Mal": ""})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := remapCategories([]string{"Anime", "mal", "tv", "TV"}, remap)
+	if want := []string{"tv"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	if _, err := NewRemap(map[string]string{"anime": "tv", "Anime": "japan"}); err == nil {
+		t.Error("categories differing only in case accepted")
+	}
+}
+
+func TestNewMatchErrors(t *testing.T) {
+	for _, between := range []string{"18:00", "25:00-02:00", "evening-night"} {
+		if _, err := NewMatch(nil, "", nil, between); err == nil {
+			t.Errorf("time window %q accepted", between)
+		}
+	}
+	if _, err := NewMatch(nil, "(", nil, ""); err == nil {
+		t.Error("invalid summary pattern accepted")
+	}
+}
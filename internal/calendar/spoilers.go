@@ -0,0 +1,107 @@
+package calendar
+
+import (
+	"reflect"
+	"time"
+
+	"github.com/jacobsee/modcal/internal/models"
+	"github.com/jacobsee/modcal/internal/plugin"
+)
+
+// Template fields that are always hidden in spoiler-free mode
+var defaultSpoilerFields = []string{"episodeTitle", "overview"}
+
+// spoilerDescription is a pseudo field that hides the whole description,
+// for events that are not rendered from template fields
+const spoilerDescription = "description"
+
+// hiddenSummary replaces the summary of events whose plugin can't render it
+// again without the hidden fields and that don't name their show
+const hiddenSummary = "Upcoming episode"
+
+// SpoilerOptions hides episode details from events until they have aired
+type SpoilerOptions struct {
+	Enabled bool
+	Fields  []string // Template fields hidden in addition to the defaults
+
+	// Reveal shows the hidden fields again once RevealDelay has passed
+	// since the event started
+	Reveal      bool
+	RevealDelay time.Duration
+}
+
+// spoilerOptions returns the options applying to an event: the calendar's
+// if set, otherwise those of the plugin instance the event came from
+func (m *Manager) spoilerOptions(calOpts *SpoilerOptions, source string) *SpoilerOptions {
+	if calOpts != nil {
+		return calOpts
+	}
+	return m.pluginManager.instanceOptions(source).Spoilers
+}
+
+// applySpoilers hides spoilers from events that have not been revealed by
+// now. Since reveals depend on the time, this runs whenever a calendar is
+// served. Events are copied so cached events are never modified.
+func (m *Manager) applySpoilers(events []models.Event, calOpts *SpoilerOptions, now time.Time) []models.Event {
+	result := make([]models.Event, len(events))
+	for i, event := range events {
+		opts := m.spoilerOptions(calOpts, event.Source)
+		if opts != nil && opts.Enabled && !opts.revealed(&event, now) {
+			m.hideSpoilers(&event, opts)
+		}
+		result[i] = event
+	}
+	return result
+}
+
+func (o *SpoilerOptions) revealed(event *models.Event, now time.Time) bool {
+	return o.Reveal && !now.Before(event.StartTime.Add(o.RevealDelay))
+}
+
+// hideSpoilers clears the spoiler fields of the event and renders its
+// summary and description again if its plugin supports it. Otherwise the
+// summary is replaced by the show and the description dropped, since either
+// may name the episode.
+func (m *Manager) hideSpoilers(event *models.Event, opts *SpoilerOptions) {
+	fields := make(map[string]interface{}, len(event.Fields))
+	for name, value := range event.Fields {
+		fields[name] = value
+	}
+
+	hideDescription := false
+	for _, list := range [][]string{defaultSpoilerFields, opts.Fields} {
+		for _, name := range list {
+			if name == spoilerDescription {
+				hideDescription = true
+				continue
+			}
+			if value, ok := fields[name]; ok {
+				fields[name] = zeroValue(value)
+			}
+		}
+	}
+	event.Fields = fields
+
+	p, _ := m.pluginManager.GetInstance(event.Source)
+	if renderer, ok := p.(plugin.Renderer); ok {
+		renderer.Render(event)
+	} else {
+		event.Summary = event.Show
+		if event.Summary == "" {
+			event.Summary = hiddenSummary
+		}
+		hideDescription = true
+	}
+	if hideDescription {
+		event.Description = ""
+	}
+}
+
+// zeroValue returns the zero value of value's type, so templates checking a
+// hidden field with "if" or "with" treat it as absent
+func zeroValue(value interface{}) interface{} {
+	if value == nil {
+		return nil
+	}
+	return reflect.Zero(reflect.TypeOf(value)).Interface()
+}
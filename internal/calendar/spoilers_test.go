@@ -0,0 +1,100 @@
+package calendar
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/jacobsee/modcal/internal/models"
+	"github.com/jacobsee/modcal/internal/plugin"
+	"github.com/jacobsee/modcal/internal/store"
+)
+
+// testPlugin is a plugin that doesn't render its events
+type testPlugin struct{}
+
+func (testPlugin) Name() string           { return "test" }
+func (testPlugin) Schema() *plugin.Schema { return nil }
+func (testPlugin) Create(map[string]interface{}, plugin.Env) (plugin.Plugin, error) {
+	return testPlugin{}, nil
+}
+func (testPlugin) FetchEvents(context.Context) ([]models.Event, error) { return nil, nil }
+
+// renderingPlugin renders summaries and descriptions from event fields
+type renderingPlugin struct{ testPlugin }
+
+func (renderingPlugin) Render(event *models.Event) {
+	event.Summary = fmt.Sprintf("%v - %v", event.Fields["show"], event.Fields["episodeTitle"])
+	event.Description = fmt.Sprint(event.Fields["overview"])
+}
+
+func TestApplySpoilers(t *testing.T) {
+	pm := NewPluginManager()
+	pm.AddInstance("trakt", renderingPlugin{}, InstanceOptions{})
+	pm.AddInstance("ics", testPlugin{}, InstanceOptions{})
+	m := NewManager(pm, &store.NoStore{}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	start := time.Date(2024, 3, 5, 20, 0, 0, 0, time.UTC)
+	trakt := models.Event{
+		UID: "trakt-1", Source: "trakt", Summary: "Severance - Hello, Ms. Cobel", Description: "Mark is promoted.",
+		StartTime: start,
+		Fields:    map[string]interface{}{"show": "Severance", "episodeTitle": "Hello, Ms. Cobel", "overview": "Mark is promoted."},
+	}
+	ics := models.Event{
+		UID: "ics-1", Source: "ics", Summary: "Severance: Hello, Ms. Cobel", Description: "Mark is promoted.",
+		StartTime: start,
+	}
+	show := ics
+	show.UID, show.Show = "ics-2", "Severance"
+
+	tests := []struct {
+		name    string
+		opts    *SpoilerOptions
+		now     time.Time
+		summary map[string]string
+		desc    map[string]string
+	}{
+		{
+			name:    "hidden",
+			opts:    &SpoilerOptions{Enabled: true},
+			now:     start.Add(-time.Hour),
+			summary: map[string]string{"trakt-1": "Severance - ", "ics-1": hiddenSummary, "ics-2": "Severance"},
+			desc:    map[string]string{"trakt-1": "", "ics-1": "", "ics-2": ""},
+		},
+		{
+			name:    "revealed",
+			opts:    &SpoilerOptions{Enabled: true, Reveal: true, RevealDelay: time.Hour},
+			now:     start.Add(time.Hour),
+			summary: map[string]string{"trakt-1": trakt.Summary, "ics-1": ics.Summary, "ics-2": show.Summary},
+			desc:    map[string]string{"trakt-1": trakt.Description, "ics-1": ics.Description, "ics-2": show.Description},
+		},
+		{
+			name:    "disabled",
+			opts:    &SpoilerOptions{},
+			now:     start.Add(-time.Hour),
+			summary: map[string]string{"trakt-1": trakt.Summary, "ics-1": ics.Summary, "ics-2": show.Summary},
+			desc:    map[string]string{"trakt-1": trakt.Description, "ics-1": ics.Description, "ics-2": show.Description},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			events := []models.Event{trakt, ics, show}
+			for _, event := range m.applySpoilers(events, tt.opts, tt.now) {
+				if event.Summary != tt.summary[event.UID] {
+					t.Errorf("%s summary = %q, want %q", event.UID, event.Summary, tt.summary[event.UID])
+				}
+				if event.Description != tt.desc[event.UID] {
+					t.Errorf("%s description = %q, want %q", event.UID, event.Description, tt.desc[event.UID])
+				}
+			}
+			// Cached events are never modified
+			if events[0].Fields["episodeTitle"] != "Hello, Ms. Cobel" || events[1].Summary != ics.Summary {
+				t.Errorf("applySpoilers changed its input: %+v", events)
+			}
+		})
+	}
+}
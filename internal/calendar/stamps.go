@@ -0,0 +1,158 @@
+package calendar
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/jacobsee/modcal/internal/models"
+	"github.com/jacobsee/modcal/internal/store"
+)
+
+// stampRetention is how long an event that is no longer served is remembered,
+// so it keeps its revision history if it comes back (e.g. a filtered view)
+const stampRetention = 30 * 24 * time.Hour
+
+// stampSaveInterval is how often stamps are saved when only their LastSeen
+// time moved, so pruning after a restart doesn't drop events still served
+const stampSaveInterval = time.Hour
+
+// stampTracker assigns stable CREATED/LAST-MODIFIED/SEQUENCE values to
+// events, moving them only when an event's content actually changes. The
+// stamps are kept in the store so they survive restarts; they are written
+// in the background so requests don't wait for the disk.
+type stampTracker struct {
+	mu        sync.Mutex
+	stamps    map[string]*store.Stamp
+	lastPrune time.Time
+	lastSave  time.Time
+	dirty     bool           // Stamps changed since they were last copied for saving
+	saving    bool           // A save goroutine is running
+	saves     sync.WaitGroup // Running save goroutines, for tests
+	store     store.Store
+	logger    *slog.Logger
+}
+
+// newStampTracker creates a tracker with the stamps saved in st
+func newStampTracker(st store.Store, logger *slog.Logger) *stampTracker {
+	t := &stampTracker{
+		stamps:   make(map[string]*store.Stamp),
+		lastSave: time.Now(),
+		store:    st,
+		logger:   logger,
+	}
+
+	saved, err := st.LoadStamps()
+	if err != nil {
+		logger.Warn("Failed to load event stamps, events will appear modified", "error", err)
+	}
+	for key, stamp := range saved {
+		t.stamps[key] = &stamp
+	}
+	return t
+}
+
+// apply updates the revision fields of events served from the given calendar
+func (t *stampTracker) apply(calendar string, events []models.Event, now time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	changed := false
+	for i := range events {
+		event := &events[i]
+		key := calendar + "\x00" + event.UID
+		if !event.RecurrenceID.IsZero() {
+			// Overrides share their series' UID
+			key += "\x00" + event.RecurrenceID.UTC().Format(time.RFC3339)
+		}
+		hash := contentHash(event)
+
+		stamp, ok := t.stamps[key]
+		switch {
+		case !ok:
+			created := now
+			if !event.Created.IsZero() && event.Created.Before(now) {
+				created = event.Created
+			}
+			stamp = &store.Stamp{
+				Hash:         hash,
+				Created:      created,
+				LastModified: now,
+				Sequence:     event.Sequence,
+			}
+			t.stamps[key] = stamp
+			changed = true
+		case stamp.Hash != hash:
+			stamp.Hash = hash
+			stamp.LastModified = now
+			stamp.Sequence++
+			changed = true
+		}
+		stamp.LastSeen = now
+
+		event.Created = stamp.Created
+		event.LastModified = stamp.LastModified
+		event.Sequence = stamp.Sequence
+	}
+
+	if now.Sub(t.lastPrune) > time.Hour {
+		for key, stamp := range t.stamps {
+			if now.Sub(stamp.LastSeen) > stampRetention {
+				delete(t.stamps, key)
+				changed = true
+			}
+		}
+		t.lastPrune = now
+	}
+
+	if changed || now.Sub(t.lastSave) > stampSaveInterval {
+		t.dirty = true
+		t.lastSave = now
+		if !t.saving {
+			t.saving = true
+			t.saves.Add(1)
+			go t.save()
+		}
+	}
+}
+
+// save writes the stamps to the store until they stop changing. Failures
+// are logged, since serving the calendar doesn't depend on them.
+func (t *stampTracker) save() {
+	defer t.saves.Done()
+	for {
+		t.mu.Lock()
+		if !t.dirty {
+			t.saving = false
+			t.mu.Unlock()
+			return
+		}
+		stamps := make(map[string]store.Stamp, len(t.stamps))
+		for key, stamp := range t.stamps {
+			stamps[key] = *stamp
+		}
+		t.dirty = false
+		t.mu.Unlock()
+
+		if err := t.store.SaveStamps(stamps); err != nil {
+			t.logger.Warn("Failed to save event stamps", "error", err)
+		}
+	}
+}
+
+// contentHash fingerprints everything about an event except its revision fields
+func contentHash(event *models.Event) [sha256.Size]byte {
+	content := *event
+	content.Created = time.Time{}
+	content.LastModified = time.Time{}
+	content.Sequence = 0
+
+	// Normalize times so the same instant in different zones hashes equally
+	content.StartTime = content.StartTime.UTC()
+	content.EndTime = content.EndTime.UTC()
+
+	data, _ := json.Marshal(content)
+	return sha256.Sum256(data)
+}
@@ -0,0 +1,64 @@
+package calendar
+
+import (
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/jacobsee/modcal/internal/models"
+	"github.com/jacobsee/modcal/internal/store"
+)
+
+// serveAfterRestart serves events from a new tracker, as after a restart,
+// and waits for the stamps to be saved
+func serveAfterRestart(st store.Store, logger *slog.Logger, calendar string, events []models.Event, now time.Time) {
+	tracker := newStampTracker(st, logger)
+	tracker.apply(calendar, events, now)
+	tracker.saves.Wait()
+}
+
+func TestStampsSurviveRestart(t *testing.T) {
+	st, err := store.NewFileStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	first := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	event := models.Event{
+		UID:       "a",
+		Summary:   "Episode 1",
+		StartTime: time.Date(2024, 3, 5, 20, 0, 0, 0, time.UTC),
+		Sequence:  2,
+	}
+
+	served := []models.Event{event}
+	serveAfterRestart(st, logger, "tv", served, first)
+	if !served[0].Created.Equal(first) || !served[0].LastModified.Equal(first) || served[0].Sequence != 2 {
+		t.Fatalf("first serve: %+v", served[0])
+	}
+
+	// After a restart, an unchanged event keeps its stamps
+	later := first.Add(24 * time.Hour)
+	served = []models.Event{event}
+	serveAfterRestart(st, logger, "tv", served, later)
+	if !served[0].Created.Equal(first) || !served[0].LastModified.Equal(first) || served[0].Sequence != 2 {
+		t.Errorf("unchanged after restart: %+v", served[0])
+	}
+
+	// A changed event is modified once, and that survives another restart
+	changed := event
+	changed.Summary = "Episode 1: Pilot"
+	served = []models.Event{changed}
+	serveAfterRestart(st, logger, "tv", served, later)
+	if !served[0].Created.Equal(first) || !served[0].LastModified.Equal(later) || served[0].Sequence != 3 {
+		t.Errorf("changed: %+v", served[0])
+	}
+
+	served = []models.Event{changed}
+	serveAfterRestart(st, logger, "tv", served, later.Add(time.Hour))
+	if !served[0].LastModified.Equal(later) || served[0].Sequence != 3 {
+		t.Errorf("changed after restart: %+v", served[0])
+	}
+}
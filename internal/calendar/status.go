@@ -0,0 +1,72 @@
+package calendar
+
+import (
+	"sort"
+	"time"
+)
+
+// PluginStatus is the refresh history of a plugin instance
+type PluginStatus struct {
+	ID          string
+	Type        string
+	LastAttempt time.Time
+	LastSuccess time.Time
+	LastError   string // Error of the last failed refresh, if any
+	LastErrorAt time.Time
+	Failures    int           // Failed refreshes since the last success
+	EventCount  int           // Events cached from the last success
+	Duration    time.Duration // Duration of the last refresh
+}
+
+// Failing reports whether the last refresh of the instance failed
+func (s PluginStatus) Failing() bool {
+	return s.Failures > 0
+}
+
+// recordRefresh updates the status and metrics of a plugin instance after a
+// refresh that started at start
+func (m *Manager) recordRefresh(pluginID, pluginType string, start time.Time, events int, err error) {
+	m.statusMu.Lock()
+	defer m.statusMu.Unlock()
+
+	status := m.pluginStatus(pluginID, pluginType)
+	status.LastAttempt = start
+	status.Duration = time.Since(start)
+	refreshDuration.Observe(status.Duration.Seconds(), pluginID, pluginType)
+	if err != nil {
+		refreshErrors.Inc(pluginID, pluginType, errorClass(err))
+		status.LastError = err.Error()
+		status.LastErrorAt = time.Now()
+		status.Failures++
+		return
+	}
+	status.LastSuccess = start
+	status.Failures = 0
+	status.EventCount = events
+}
+
+// pluginStatus returns the status of a plugin instance, creating it if
+// needed. statusMu must be held.
+func (m *Manager) pluginStatus(pluginID, pluginType string) *PluginStatus {
+	status, ok := m.status[pluginID]
+	if !ok {
+		status = &PluginStatus{ID: pluginID, Type: pluginType}
+		m.status[pluginID] = status
+	}
+	return status
+}
+
+// PluginStatuses returns the status of every plugin instance, sorted by ID
+func (m *Manager) PluginStatuses() []PluginStatus {
+	instances := m.pluginManager.snapshot()
+
+	m.statusMu.Lock()
+	defer m.statusMu.Unlock()
+
+	statuses := make([]PluginStatus, 0, len(instances))
+	for id, p := range instances {
+		statuses = append(statuses, *m.pluginStatus(id, p.Name()))
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].ID < statuses[j].ID })
+	return statuses
+}
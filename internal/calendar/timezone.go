@@ -0,0 +1,32 @@
+package calendar
+
+import (
+	"github.com/jacobsee/modcal/internal/models"
+)
+
+// applyTimeZone moves events into the calendar's output zone. The instants
+// are unchanged; only the zone the times are expressed in differs. All-day
+// events keep their dates, and recurring series that have a zone of their
+// own keep it, since it defines when their occurrences happen.
+func applyTimeZone(events []models.Event, name string) []models.Event {
+	if name == "" {
+		return events
+	}
+	loc, err := models.LoadZone(name)
+	if err != nil {
+		return events
+	}
+
+	result := make([]models.Event, len(events))
+	for i, event := range events {
+		if !event.AllDay && !(event.IsRecurring() && event.TimeZone != "") {
+			event.TimeZone = name
+			event.StartTime = event.StartTime.In(loc)
+			if !event.EndTime.IsZero() {
+				event.EndTime = event.EndTime.In(loc)
+			}
+		}
+		result[i] = event
+	}
+	return result
+}
@@ -1,7 +1,7 @@
 package config
 
 import (
-	"os"
+	"path/filepath"
 	"time"
 
 	"gopkg.in/yaml.v3"
@@ -9,11 +9,14 @@ import (
 
 // Config represents the main application configuration
 type Config struct {
-	Server    ServerConfig              `yaml:"server"`
-	Auth      AuthConfig                `yaml:"auth"`
-	Plugins   []PluginConfig            `yaml:"plugins"`
-	Calendars []CalendarConfig          `yaml:"calendars"`
-	Scheduler SchedulerConfig           `yaml:"scheduler"`
+	Server    ServerConfig     `yaml:"server"`
+	Auth      AuthConfig       `yaml:"auth"`
+	Plugins   []PluginConfig   `yaml:"plugins"`
+	Calendars []CalendarConfig `yaml:"calendars"`
+	Scheduler SchedulerConfig  `yaml:"scheduler"`
+	Storage   StorageConfig    `yaml:"storage"`
+	Tokens    TokensConfig     `yaml:"tokens"`
+	Logging   LoggingConfig    `yaml:"logging"`
 }
 
 // ServerConfig contains HTTP server settings
@@ -33,33 +36,128 @@ type PluginConfig struct {
 	ID     string                 `yaml:"id"`
 	Type   string                 `yaml:"type"`
 	Config map[string]interface{} `yaml:"config,omitempty"`
+	Alarms []AlarmConfig          `yaml:"alarms,omitempty"`
+
+	// Refresh schedule, either an interval or a cron expression such as
+	// "0 6 * * *"; defaults to the scheduler's interval
+	Interval time.Duration `yaml:"interval,omitempty"`
+	Schedule string        `yaml:"schedule,omitempty"`
+
+	SpoilerFree *SpoilerConfig `yaml:"spoilerFree,omitempty"`
 }
 
 // CalendarConfig represents a calendar that aggregates plugin events
 type CalendarConfig struct {
-	Name        string   `yaml:"name"`
-	Description string   `yaml:"description"`
-	PluginIDs   []string `yaml:"plugins"`
+	Name        string        `yaml:"name"`
+	Description string        `yaml:"description"`
+	PluginIDs   []string      `yaml:"plugins"`
+	Alarms      []AlarmConfig `yaml:"alarms,omitempty"`
+	TimeZone    string        `yaml:"timezone,omitempty"` // IANA zone, e.g. "America/New_York"
+	Rules       []RuleConfig  `yaml:"rules,omitempty"`
+	Dedup       *DedupConfig  `yaml:"dedup,omitempty"`
+
+	SpoilerFree *SpoilerConfig `yaml:"spoilerFree,omitempty"` // Overrides the plugins' settings
 }
 
-// SchedulerConfig contains event fetching schedule settings
-type SchedulerConfig struct {
-	Interval time.Duration `yaml:"interval"`
+// SpoilerConfig hides episode titles, synopses and other template fields
+// from events. It is either a boolean or the full options; the options
+// imply enabled unless set otherwise.
+type SpoilerConfig struct {
+	Enabled     bool          `yaml:"enabled"`
+	Fields      []string      `yaml:"fields,omitempty"`      // Hidden in addition to episodeTitle and overview
+	Reveal      bool          `yaml:"reveal,omitempty"`      // Show the fields once the event has aired
+	RevealDelay time.Duration `yaml:"revealDelay,omitempty"` // Time after the start until the reveal
 }
 
-// LoadFromFile loads configuration from a YAML file
-func LoadFromFile(path string) (*Config, error) {
-	data, err := os.ReadFile(path)
-	if err != nil {
-		return nil, err
+// UnmarshalYAML accepts "spoilerFree: true" as well as a mapping of options
+func (c *SpoilerConfig) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		*c = SpoilerConfig{}
+		return node.Decode(&c.Enabled)
 	}
 
-	var cfg Config
-	if err := yaml.Unmarshal(data, &cfg); err != nil {
-		return nil, err
+	type options SpoilerConfig
+	opts := options{Enabled: true}
+	if err := node.Decode(&opts); err != nil {
+		return err
 	}
+	*c = SpoilerConfig(opts)
+	return nil
+}
+
+// DedupConfig enables merging of duplicate events from different plugins.
+// Prefer lists plugin IDs by preference; Fields overrides the preference
+// for single fields, e.g. "url" or "description".
+type DedupConfig struct {
+	Window time.Duration       `yaml:"window,omitempty"` // Maximum start time difference (default: 2h)
+	Prefer []string            `yaml:"prefer,omitempty"`
+	Fields map[string][]string `yaml:"fields,omitempty"`
+}
+
+// RuleConfig represents a filter or transformation of a calendar's events.
+// Include keeps only matching events, Exclude drops matching events, and
+// the transformations apply to events matching When (or all events).
+type RuleConfig struct {
+	Include *MatchConfig `yaml:"include,omitempty"`
+	Exclude *MatchConfig `yaml:"exclude,omitempty"`
+	When    *MatchConfig `yaml:"when,omitempty"`
+
+	Rewrite    []RewriteConfig   `yaml:"rewrite,omitempty"`
+	Prefix     string            `yaml:"prefix,omitempty"`
+	Categories map[string]string `yaml:"categories,omitempty"` // Category remapping, "" removes
+}
+
+// MatchConfig represents a condition on events. All set fields must match.
+type MatchConfig struct {
+	Categories []string `yaml:"categories,omitempty"` // Any of these categories
+	Summary    string   `yaml:"summary,omitempty"`    // Regular expression
+	Shows      []string `yaml:"shows,omitempty"`      // Any of these show names
+	Between    string   `yaml:"between,omitempty"`    // Time of day range, e.g. "18:00-23:30"
+}
+
+// RewriteConfig represents a regular expression replacement on summaries
+type RewriteConfig struct {
+	Pattern string `yaml:"pattern"`
+	Replace string `yaml:"replace"`
+}
+
+// AlarmConfig represents a reminder added to every event. Either Before
+// (e.g. 15m before the event starts) or At (a time of day such as "09:00"
+// on the day of the event, optionally DaysBefore days earlier) is used.
+type AlarmConfig struct {
+	Before      time.Duration `yaml:"before,omitempty"`
+	At          string        `yaml:"at,omitempty"`
+	DaysBefore  int           `yaml:"daysBefore,omitempty"`
+	Description string        `yaml:"description,omitempty"`
+}
 
-	// Set defaults
+// SchedulerConfig contains event fetching schedule settings. Interval is
+// the default for plugins without a schedule of their own.
+type SchedulerConfig struct {
+	Interval time.Duration  `yaml:"interval"`
+	Jitter   *time.Duration `yaml:"jitter,omitempty"` // Maximum random delay of each refresh
+}
+
+// StorageConfig contains settings for persisting fetched events
+type StorageConfig struct {
+	Type string `yaml:"type"`           // "memory" or "file"
+	Path string `yaml:"path,omitempty"` // Directory of the file store
+}
+
+// TokensConfig contains settings for persisting OAuth tokens, which plugins
+// refresh on their own
+type TokensConfig struct {
+	Path string `yaml:"path,omitempty"` // JSON file
+}
+
+// LoggingConfig contains log output settings
+type LoggingConfig struct {
+	Level  string `yaml:"level"`  // "debug", "info", "warn" or "error"
+	Format string `yaml:"format"` // "text" or "json"
+}
+
+// ApplyDefaults sets the defaults of options left out of the config file
+func (cfg *Config) ApplyDefaults() {
 	if cfg.Server.Port == 0 {
 		cfg.Server.Port = 8080
 	}
@@ -69,9 +167,28 @@ func LoadFromFile(path string) (*Config, error) {
 	if cfg.Scheduler.Interval == 0 {
 		cfg.Scheduler.Interval = 15 * time.Minute
 	}
+	if cfg.Scheduler.Jitter == nil {
+		jitter := 30 * time.Second
+		cfg.Scheduler.Jitter = &jitter
+	}
 	if cfg.Auth.Method == "" {
 		cfg.Auth.Method = "none"
 	}
-
-	return &cfg, nil
+	if cfg.Storage.Type == "" {
+		cfg.Storage.Type = "memory"
+	}
+	// Tokens are always kept in a file: refresh tokens may be single use,
+	// so after a restart the configured one may no longer work
+	if cfg.Tokens.Path == "" {
+		cfg.Tokens.Path = "tokens.json"
+		if cfg.Storage.Type == "file" && cfg.Storage.Path != "" {
+			cfg.Tokens.Path = filepath.Join(cfg.Storage.Path, "tokens.json")
+		}
+	}
+	if cfg.Logging.Level == "" {
+		cfg.Logging.Level = "info"
+	}
+	if cfg.Logging.Format == "" {
+		cfg.Logging.Format = "text"
+	}
 }
@@ -0,0 +1,103 @@
+package feed
+
+import (
+	"encoding/xml"
+	"net/url"
+	"time"
+
+	"github.com/jacobsee/modcal/internal/models"
+)
+
+type atomFeed struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	ID      string      `xml:"id"`
+	Title   string      `xml:"title"`
+	Updated string      `xml:"updated"`
+	Author  atomPerson  `xml:"author"`
+	Links   []atomLink  `xml:"link"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomPerson struct {
+	Name string `xml:"name"`
+}
+
+type atomLink struct {
+	Rel  string `xml:"rel,attr,omitempty"`
+	Type string `xml:"type,attr,omitempty"`
+	Href string `xml:"href,attr"`
+}
+
+type atomEntry struct {
+	ID         string         `xml:"id"`
+	Title      string         `xml:"title"`
+	Updated    string         `xml:"updated"`
+	Published  string         `xml:"published"`
+	Links      []atomLink     `xml:"link"`
+	Categories []atomCategory `xml:"category"`
+	Content    atomContent    `xml:"content"`
+}
+
+type atomCategory struct {
+	Term string `xml:"term,attr"`
+}
+
+type atomContent struct {
+	Type string `xml:"type,attr"`
+	Body string `xml:",chardata"`
+}
+
+// Atom renders the calendar's upcoming events as an Atom feed. Each entry is
+// published at the event's start time.
+func Atom(cal *models.Calendar, opts Options) ([]byte, error) {
+	now := time.Now()
+	events := entries(cal, now)
+
+	feed := atomFeed{
+		ID:     "urn:modcal:calendar:" + url.PathEscape(cal.Name),
+		Title:  calendarTitle(cal),
+		Author: atomPerson{Name: "modcal"},
+	}
+	if opts.Self != "" {
+		feed.Links = append(feed.Links, atomLink{Rel: "self", Type: "application/atom+xml", Href: opts.Self})
+	}
+	if opts.Link != "" {
+		feed.Links = append(feed.Links, atomLink{Rel: "alternate", Href: opts.Link})
+	}
+
+	var latest time.Time
+	for i := range events {
+		event := &events[i]
+
+		entry := atomEntry{
+			ID:        entryID(event),
+			Title:     event.Summary,
+			Updated:   updated(event).UTC().Format(time.RFC3339),
+			Published: event.StartTime.UTC().Format(time.RFC3339),
+			Content:   atomContent{Type: "text", Body: content(event)},
+		}
+		if event.URL != "" {
+			entry.Links = append(entry.Links, atomLink{Rel: "alternate", Href: event.URL})
+		}
+		for _, category := range event.Categories {
+			entry.Categories = append(entry.Categories, atomCategory{Term: category})
+		}
+		feed.Entries = append(feed.Entries, entry)
+
+		if t := updated(event); t.After(latest) && !t.After(now) {
+			latest = t
+		}
+	}
+
+	// A feed without entries, or with only future ones, is current as of now
+	if latest.IsZero() {
+		latest = now
+	}
+	feed.Updated = latest.UTC().Format(time.RFC3339)
+
+	data, err := xml.MarshalIndent(feed, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), data...), nil
+}
@@ -0,0 +1,90 @@
+package feed
+
+import (
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jacobsee/modcal/internal/models"
+	"github.com/jacobsee/modcal/internal/recurrence"
+)
+
+const (
+	// Feeds list events starting within this window around now
+	windowBack    = 7 * 24 * time.Hour
+	windowForward = 30 * 24 * time.Hour
+)
+
+// Options describes where a feed is served from
+type Options struct {
+	Self string // URL of the feed itself
+	Link string // URL of the calendar the feed belongs to
+}
+
+// entries returns the events a feed lists: occurrences starting within the
+// calendar's range, or the feed window if it has none, sorted by start time
+func entries(cal *models.Calendar, now time.Time) []models.Event {
+	from, to := now.Add(-windowBack), now.Add(windowForward)
+	if !cal.From.IsZero() {
+		from, to = cal.From, cal.To
+	}
+
+	var events []models.Event
+	for _, event := range recurrence.Expand(cal.Events, from, to) {
+		if !event.StartTime.Before(from) && event.StartTime.Before(to) {
+			events = append(events, event)
+		}
+	}
+
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].StartTime.Before(events[j].StartTime)
+	})
+	return events
+}
+
+// airTime formats the event's start in its own zone
+func airTime(event *models.Event) string {
+	start := event.StartTime
+	if loc := event.Zone(); loc != nil {
+		start = start.In(loc)
+	}
+	if event.AllDay {
+		return start.Format("Mon, 02 Jan 2006")
+	}
+	return start.Format("Mon, 02 Jan 2006 15:04 MST")
+}
+
+// content is the entry body: the air time followed by the description
+func content(event *models.Event) string {
+	text := "Airs " + airTime(event)
+	if event.Description != "" {
+		text += "\n\n" + event.Description
+	}
+	return text
+}
+
+// updated returns when the event last changed, falling back to its start
+func updated(event *models.Event) time.Time {
+	switch {
+	case !event.LastModified.IsZero():
+		return event.LastModified
+	case !event.Created.IsZero():
+		return event.Created
+	default:
+		return event.StartTime
+	}
+}
+
+// entryID returns a stable identifier for an event, used as the Atom id and
+// the RSS guid
+func entryID(event *models.Event) string {
+	return "urn:modcal:event:" + url.PathEscape(event.UID)
+}
+
+func calendarTitle(cal *models.Calendar) string {
+	if cal.Description != "" && !strings.EqualFold(cal.Description, cal.Name) {
+		return cal.Name + " - " + cal.Description
+	}
+	return cal.Name
+}
@@ -0,0 +1,91 @@
+package feed
+
+import (
+	"encoding/xml"
+	"time"
+
+	"github.com/jacobsee/modcal/internal/models"
+)
+
+type rssFeed struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	Atom    string     `xml:"xmlns:atom,attr,omitempty"`
+	Channel rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title         string       `xml:"title"`
+	Link          string       `xml:"link"`
+	Description   string       `xml:"description"`
+	LastBuildDate string       `xml:"lastBuildDate"`
+	Generator     string       `xml:"generator"`
+	Self          *rssAtomLink `xml:"atom:link,omitempty"`
+	Items         []rssItem    `xml:"item"`
+}
+
+// rssAtomLink is the atom:link element RSS feeds use to point to themselves
+type rssAtomLink struct {
+	Rel  string `xml:"rel,attr"`
+	Type string `xml:"type,attr"`
+	Href string `xml:"href,attr"`
+}
+
+type rssItem struct {
+	Title       string   `xml:"title"`
+	Link        string   `xml:"link,omitempty"`
+	Description string   `xml:"description"`
+	Categories  []string `xml:"category"`
+	GUID        rssGUID  `xml:"guid"`
+	PubDate     string   `xml:"pubDate"`
+}
+
+type rssGUID struct {
+	IsPermaLink string `xml:"isPermaLink,attr"`
+	Value       string `xml:",chardata"`
+}
+
+// RSS renders the calendar's upcoming events as an RSS 2.0 feed. Each item's
+// publication date is the event's start time.
+func RSS(cal *models.Calendar, opts Options) ([]byte, error) {
+	now := time.Now()
+
+	description := cal.Description
+	if description == "" {
+		description = "Upcoming events from " + cal.Name
+	}
+
+	feed := rssFeed{
+		Version: "2.0",
+		Channel: rssChannel{
+			Title:         calendarTitle(cal),
+			Link:          opts.Link,
+			Description:   description,
+			LastBuildDate: now.UTC().Format(time.RFC1123Z),
+			Generator:     "modcal",
+		},
+	}
+	if opts.Self != "" {
+		feed.Atom = "http://www.w3.org/2005/Atom"
+		feed.Channel.Self = &rssAtomLink{Rel: "self", Type: "application/rss+xml", Href: opts.Self}
+	}
+
+	events := entries(cal, now)
+	for i := range events {
+		event := &events[i]
+		feed.Channel.Items = append(feed.Channel.Items, rssItem{
+			Title:       event.Summary,
+			Link:        event.URL,
+			Description: content(event),
+			Categories:  event.Categories,
+			GUID:        rssGUID{IsPermaLink: "false", Value: entryID(event)},
+			PubDate:     event.StartTime.UTC().Format(time.RFC1123Z),
+		})
+	}
+
+	data, err := xml.MarshalIndent(feed, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), data...), nil
+}
@@ -0,0 +1,83 @@
+package ical
+
+import (
+	"net/url"
+	"strings"
+	"unicode/utf8"
+)
+
+// maxLineOctets is the longest a content line may be, excluding the CRLF
+const maxLineOctets = 75
+
+// lineWriter writes RFC 5545 content lines, folding long lines
+type lineWriter struct {
+	builder strings.Builder
+}
+
+// line writes a property whose value is already in its final encoding
+func (w *lineWriter) line(name, value string) {
+	w.fold(name + ":" + value)
+}
+
+// fold splits a content line into chunks of at most 75 octets without
+// breaking multi-byte characters, continuing each chunk with a space
+func (w *lineWriter) fold(line string) {
+	limit := maxLineOctets
+	for len(line) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(line[cut]) {
+			cut--
+		}
+		w.builder.WriteString(line[:cut])
+		w.builder.WriteString("\r\n ")
+		line = line[cut:]
+		// The leading space of a continuation line counts towards its length
+		limit = maxLineOctets - 1
+	}
+	w.builder.WriteString(line)
+	w.builder.WriteString("\r\n")
+}
+
+func (w *lineWriter) String() string {
+	return w.builder.String()
+}
+
+func validURI(value string) bool {
+	if value == "" || strings.ContainsAny(value, " \t\r\n\"") {
+		return false
+	}
+	for _, c := range value {
+		if c < 0x20 || c == 0x7f {
+			return false
+		}
+	}
+	u, err := url.Parse(value)
+	return err == nil && u.Scheme != ""
+}
+
+func escapeText(text string) string {
+	text = strings.ToValidUTF8(text, "\uFFFD")
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	text = strings.ReplaceAll(text, "\r", "\n")
+
+	var builder strings.Builder
+	for _, c := range text {
+		switch {
+		case c == '\\':
+			builder.WriteString("\\\\")
+		case c == ';':
+			builder.WriteString("\\;")
+		case c == ',':
+			builder.WriteString("\\,")
+		case c == '\n':
+			builder.WriteString("\\n")
+		case c == '\t':
+			builder.WriteRune(c)
+		case c < 0x20 || c == 0x7f:
+			// Other control characters are not allowed in TEXT values
+		default:
+			builder.WriteRune(c)
+		}
+	}
+	return builder.String()
+}
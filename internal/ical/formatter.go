@@ -1,7 +1,7 @@
 package ical
 
 import (
-	"fmt"
+	"strconv"
 	"strings"
 	"time"
 
@@ -15,65 +15,142 @@ const (
 
 // Format converts a calendar model as an iCal
 func Format(cal *models.Calendar) string {
-	var builder strings.Builder
+	w := &lineWriter{}
+	buildCalendar(cal).writeText(w)
+	return w.String()
+}
+
+// buildCalendar converts a calendar model to its VCALENDAR component
+func buildCalendar(cal *models.Calendar) *node {
+	root := newNode("VCALENDAR")
 
-	builder.WriteString("BEGIN:VCALENDAR\r\n")
-	builder.WriteString("VERSION:2.0\r\n")
-	builder.WriteString("PRODID:-//modcal//modcal//EN\r\n")
-	builder.WriteString(fmt.Sprintf("X-WR-CALNAME:%s\r\n", escapeText(cal.Name)))
-	if cal.Description != "" {
-		builder.WriteString(fmt.Sprintf("X-WR-CALDESC:%s\r\n", escapeText(cal.Description)))
+	root.text("VERSION", "2.0")
+	root.text("PRODID", "-//modcal//modcal//EN")
+	root.text("X-WR-CALNAME", cal.Name)
+	root.text("X-WR-CALDESC", cal.Description)
+	root.text("X-WR-TIMEZONE", cal.TimeZone)
+
+	zones := collectZones(cal.Events)
+	for _, name := range zones.names {
+		buildTimezone(root, name, zones.zones[name], zones.from[name], zones.to[name])
 	}
 
 	for _, event := range cal.Events {
-		builder.WriteString(formatEvent(&event))
+		buildEvent(root, &event)
 	}
 
-	builder.WriteString("END:VCALENDAR\r\n")
-
-	return builder.String()
+	return root
 }
 
-func formatEvent(event *models.Event) string {
-	var builder strings.Builder
+func buildEvent(cal *node, event *models.Event) {
+	n := cal.child("VEVENT")
+	n.text("UID", event.UID)
+
+	// DTSTAMP only moves when the event changes, so clients polling the feed
+	// don't treat every event as updated on every request
+	stamp := event.LastModified
+	if stamp.IsZero() {
+		stamp = time.Now()
+	}
+	n.utc("DTSTAMP", stamp)
+	if !event.Created.IsZero() {
+		n.utc("CREATED", event.Created)
+	}
+	if !event.LastModified.IsZero() {
+		n.utc("LAST-MODIFIED", event.LastModified)
+	}
+	if event.Sequence > 0 {
+		n.integer("SEQUENCE", event.Sequence)
+	}
 
-	builder.WriteString("BEGIN:VEVENT\r\n")
-	builder.WriteString(fmt.Sprintf("UID:%s\r\n", escapeText(event.UID)))
-	builder.WriteString(fmt.Sprintf("DTSTAMP:%s\r\n", formatDateTime(time.Now())))
+	// Times are written in UTC unless the event has a zone, in which case
+	// they are local times referencing the zone's VTIMEZONE
+	loc := event.Zone()
+	n.times("DTSTART", event.AllDay, loc, event.StartTime)
+	if !event.EndTime.IsZero() {
+		n.times("DTEND", event.AllDay, loc, event.EndTime)
+	}
 
-	if event.AllDay {
-		builder.WriteString(fmt.Sprintf("DTSTART;VALUE=DATE:%s\r\n", formatDate(event.StartTime)))
-		if !event.EndTime.IsZero() {
-			builder.WriteString(fmt.Sprintf("DTEND;VALUE=DATE:%s\r\n", formatDate(event.EndTime)))
-		}
-	} else {
-		builder.WriteString(fmt.Sprintf("DTSTART:%s\r\n", formatDateTime(event.StartTime)))
-		if !event.EndTime.IsZero() {
-			builder.WriteString(fmt.Sprintf("DTEND:%s\r\n", formatDateTime(event.EndTime)))
+	if !event.RecurrenceID.IsZero() {
+		n.times("RECURRENCE-ID", event.AllDay, loc, event.RecurrenceID)
+	}
+
+	if event.IsRecurring() {
+		if event.Recurrence.Rule != "" {
+			n.recur("RRULE", event.Recurrence.Rule)
 		}
+		n.times("RDATE", event.AllDay, loc, event.Recurrence.RDates...)
+		n.times("EXDATE", event.AllDay, loc, event.Recurrence.ExDates...)
+	}
+
+	n.text("SUMMARY", event.Summary)
+	n.text("DESCRIPTION", event.Description)
+	n.text("LOCATION", event.Location)
+
+	if event.URL != "" {
+		n.uri("URL", event.URL)
 	}
 
-	if event.Summary != "" {
-		builder.WriteString(fmt.Sprintf("SUMMARY:%s\r\n", escapeText(event.Summary)))
+	n.text("CATEGORIES", event.Categories...)
+
+	for _, alarm := range event.Alarms {
+		buildAlarm(n, event, alarm)
 	}
+}
 
-	if event.Description != "" {
-		builder.WriteString(fmt.Sprintf("DESCRIPTION:%s\r\n", escapeText(event.Description)))
+func buildAlarm(event *node, source *models.Event, alarm models.Alarm) {
+	n := event.child("VALARM")
+	n.text("ACTION", "DISPLAY")
+
+	description := alarm.Description
+	if description == "" {
+		description = source.Summary
+	}
+	if description == "" {
+		description = "Reminder"
 	}
+	n.text("DESCRIPTION", description)
 
-	if event.Location != "" {
-		builder.WriteString(fmt.Sprintf("LOCATION:%s\r\n", escapeText(event.Location)))
+	if !alarm.At.IsZero() {
+		n.utc("TRIGGER", alarm.At)
+	} else {
+		n.duration("TRIGGER", -alarm.Before)
 	}
+}
 
-	if event.URL != "" {
-		builder.WriteString(fmt.Sprintf("URL:%s\r\n", event.URL))
+// formatDuration formats a duration as an RFC 5545 dur-value, e.g. "-PT15M"
+func formatDuration(d time.Duration) string {
+	var builder strings.Builder
+	if d < 0 {
+		builder.WriteString("-")
+		d = -d
 	}
+	builder.WriteString("P")
 
-	if len(event.Categories) > 0 {
-		builder.WriteString(fmt.Sprintf("CATEGORIES:%s\r\n", strings.Join(event.Categories, ",")))
+	days := d / (24 * time.Hour)
+	d -= days * 24 * time.Hour
+	if days > 0 {
+		builder.WriteString(strconv.Itoa(int(days)) + "D")
 	}
 
-	builder.WriteString("END:VEVENT\r\n")
+	if d > 0 || days == 0 {
+		builder.WriteString("T")
+		hours := d / time.Hour
+		d -= hours * time.Hour
+		minutes := d / time.Minute
+		d -= minutes * time.Minute
+		seconds := d / time.Second
+
+		if hours > 0 {
+			builder.WriteString(strconv.Itoa(int(hours)) + "H")
+		}
+		if minutes > 0 {
+			builder.WriteString(strconv.Itoa(int(minutes)) + "M")
+		}
+		if seconds > 0 || (hours == 0 && minutes == 0) {
+			builder.WriteString(strconv.Itoa(int(seconds)) + "S")
+		}
+	}
 
 	return builder.String()
 }
@@ -85,11 +162,3 @@ func formatDateTime(t time.Time) string {
 func formatDate(t time.Time) string {
 	return t.Format(dateFormat)
 }
-
-func escapeText(text string) string {
-	text = strings.ReplaceAll(text, "\\", "\\\\")
-	text = strings.ReplaceAll(text, ";", "\\;")
-	text = strings.ReplaceAll(text, ",", "\\,")
-	text = strings.ReplaceAll(text, "\n", "\\n")
-	return text
-}
@@ -0,0 +1,142 @@
+package ical
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jacobsee/modcal/internal/models"
+)
+
+var jcalTypes = map[valueKind]string{
+	kindText:      "text",
+	kindURI:       "uri",
+	kindDateTime:  "date-time",
+	kindDate:      "date",
+	kindDuration:  "duration",
+	kindRecur:     "recur",
+	kindInteger:   "integer",
+	kindUTCOffset: "utc-offset",
+}
+
+// recurListParts are RRULE parts that hold a list of values in jCal
+var recurListParts = map[string]bool{
+	"bysecond":   true,
+	"byminute":   true,
+	"byhour":     true,
+	"byday":      true,
+	"bymonthday": true,
+	"byyearday":  true,
+	"byweekno":   true,
+	"bymonth":    true,
+	"bysetpos":   true,
+}
+
+// FormatJCal converts a calendar model to jCal (RFC 7265), the JSON
+// representation of iCalendar
+func FormatJCal(cal *models.Calendar) ([]byte, error) {
+	return json.Marshal(buildCalendar(cal).jcal())
+}
+
+// jcal returns the node as a jCal component: [name, properties, components]
+func (n *node) jcal() []interface{} {
+	properties := make([]interface{}, 0, len(n.properties))
+	for _, p := range n.properties {
+		properties = append(properties, p.jcal())
+	}
+
+	components := make([]interface{}, 0, len(n.children))
+	for _, c := range n.children {
+		components = append(components, c.jcal())
+	}
+
+	return []interface{}{strings.ToLower(n.name), properties, components}
+}
+
+// jcal returns the property as [name, parameters, type, value...]
+func (p property) jcal() []interface{} {
+	params := map[string]string{}
+	if p.tzid != "" {
+		params["tzid"] = p.tzid
+	}
+
+	result := []interface{}{strings.ToLower(p.name), params, jcalTypes[p.kind]}
+	for _, v := range p.values {
+		result = append(result, p.jcalValue(v))
+	}
+	return result
+}
+
+func (p property) jcalValue(v propertyValue) interface{} {
+	switch p.kind {
+	case kindDateTime:
+		if v.floating {
+			return v.time.Format("2006-01-02T15:04:05")
+		}
+		return v.time.UTC().Format("2006-01-02T15:04:05Z")
+	case kindDate:
+		return v.time.Format("2006-01-02")
+	case kindDuration:
+		return formatDuration(v.duration)
+	case kindRecur:
+		return jcalRecur(v.text)
+	case kindInteger:
+		return v.integer
+	case kindUTCOffset:
+		offset := formatUTCOffset(v.integer)
+		result := offset[:3] + ":" + offset[3:5]
+		if len(offset) > 5 {
+			result += ":" + offset[5:]
+		}
+		return result
+	default:
+		return v.text
+	}
+}
+
+// jcalRecur converts an RRULE value such as "FREQ=WEEKLY;BYDAY=MO,WE" to
+// its jCal object form
+func jcalRecur(rule string) map[string]interface{} {
+	result := make(map[string]interface{})
+	for _, part := range strings.Split(rule, ";") {
+		key, value, ok := strings.Cut(part, "=")
+		if !ok {
+			continue
+		}
+		key = strings.ToLower(key)
+
+		var values []interface{}
+		for _, item := range strings.Split(value, ",") {
+			values = append(values, jcalRecurValue(key, item))
+		}
+
+		if recurListParts[key] && len(values) > 1 {
+			result[key] = values
+		} else {
+			result[key] = values[0]
+		}
+	}
+	return result
+}
+
+func jcalRecurValue(key, value string) interface{} {
+	switch key {
+	case "until":
+		if t, err := time.Parse(dateTimeFormat, value); err == nil {
+			return t.Format("2006-01-02T15:04:05Z")
+		}
+		if t, err := time.Parse(dateFormat, value); err == nil {
+			return t.Format("2006-01-02")
+		}
+		if t, err := time.Parse(localDateTimeFormat, value); err == nil {
+			return t.Format("2006-01-02T15:04:05")
+		}
+	case "freq", "wkst", "byday":
+	default:
+		if n, err := strconv.Atoi(value); err == nil {
+			return n
+		}
+	}
+	return value
+}
@@ -0,0 +1,139 @@
+package ical
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/jacobsee/modcal/internal/models"
+)
+
+func TestPropertyJCal(t *testing.T) {
+	berlin := mustZone(t, "Europe/Berlin")
+	start := time.Date(2024, 3, 4, 20, 0, 0, 0, berlin)
+
+	tests := []struct {
+		name string
+		prop func(n *node)
+		want string
+	}{
+		{
+			name: "date-time with tzid",
+			prop: func(n *node) { n.times("DTSTART", false, berlin, start) },
+			want: `["dtstart",{"tzid":"Europe/Berlin"},"date-time","2024-03-04T20:00:00"]`,
+		},
+		{
+			name: "date-time in UTC",
+			prop: func(n *node) { n.times("DTSTART", false, nil, start) },
+			want: `["dtstart",{},"date-time","2024-03-04T19:00:00Z"]`,
+		},
+		{
+			name: "date list",
+			prop: func(n *node) { n.times("EXDATE", true, nil, start, start.AddDate(0, 0, 7)) },
+			want: `["exdate",{},"date","2024-03-04","2024-03-11"]`,
+		},
+		{
+			name: "utc-offset",
+			prop: func(n *node) {
+				n.add(property{name: "TZOFFSETTO", kind: kindUTCOffset, values: []propertyValue{{integer: 3600}}})
+			},
+			want: `["tzoffsetto",{},"utc-offset","+01:00"]`,
+		},
+		{
+			// Local mean times before standard zones have offsets in seconds
+			name: "utc-offset with seconds",
+			prop: func(n *node) {
+				n.add(property{name: "TZOFFSETFROM", kind: kindUTCOffset, values: []propertyValue{{integer: -(17*60 + 32)}}})
+			},
+			want: `["tzoffsetfrom",{},"utc-offset","-00:17:32"]`,
+		},
+		{
+			name: "recur list parts",
+			prop: func(n *node) { n.recur("RRULE", "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,WE;BYMONTH=3;BYSETPOS=-1") },
+			want: `["rrule",{},"recur",{"byday":["MO","WE"],"bymonth":3,"bysetpos":-1,"freq":"WEEKLY","interval":2}]`,
+		},
+		{
+			name: "recur until a date-time",
+			prop: func(n *node) { n.recur("RRULE", "FREQ=DAILY;UNTIL=20240401T000000Z") },
+			want: `["rrule",{},"recur",{"freq":"DAILY","until":"2024-04-01T00:00:00Z"}]`,
+		},
+		{
+			name: "recur until a date",
+			prop: func(n *node) { n.recur("RRULE", "FREQ=DAILY;UNTIL=20240401") },
+			want: `["rrule",{},"recur",{"freq":"DAILY","until":"2024-04-01"}]`,
+		},
+		{
+			name: "integer",
+			prop: func(n *node) { n.integer("SEQUENCE", 2) },
+			want: `["sequence",{},"integer",2]`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			n := newNode("VEVENT")
+			tt.prop(n)
+			got, err := json.Marshal(n.properties[0].jcal())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("got  %s\nwant %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormatJCal(t *testing.T) {
+	start := time.Date(2024, 3, 4, 20, 0, 0, 0, mustZone(t, "Europe/Berlin"))
+	cal := &models.Calendar{
+		Name: "TV",
+		Events: []models.Event{{
+			UID: "series", Summary: "News", TimeZone: "Europe/Berlin",
+			StartTime: start, EndTime: start.Add(time.Hour), LastModified: start,
+			Recurrence: &models.Recurrence{Rule: "FREQ=WEEKLY;COUNT=3"},
+		}},
+	}
+
+	data, err := FormatJCal(cal)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var root []json.RawMessage
+	if err := json.Unmarshal(data, &root); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	if len(root) != 3 || string(root[0]) != `"vcalendar"` {
+		t.Fatalf("root = %s, want [\"vcalendar\", properties, components]", data)
+	}
+
+	var components [][]json.RawMessage
+	if err := json.Unmarshal(root[2], &components); err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, c := range components {
+		names = append(names, string(c[0]))
+	}
+	if len(names) != 2 || names[0] != `"vtimezone"` || names[1] != `"vevent"` {
+		t.Fatalf("components = %v, want vtimezone and vevent", names)
+	}
+
+	var properties []json.RawMessage
+	if err := json.Unmarshal(components[1][1], &properties); err != nil {
+		t.Fatal(err)
+	}
+	found := map[string]bool{}
+	for _, p := range properties {
+		found[string(p)] = true
+	}
+	for _, want := range []string{
+		`["dtstart",{"tzid":"Europe/Berlin"},"date-time","2024-03-04T20:00:00"]`,
+		`["dtstamp",{},"date-time","2024-03-04T19:00:00Z"]`,
+		`["rrule",{},"recur",{"count":3,"freq":"WEEKLY"}]`,
+	} {
+		if !found[want] {
+			t.Errorf("missing %s in %s", want, components[1][1])
+		}
+	}
+}
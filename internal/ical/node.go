@@ -0,0 +1,212 @@
+package ical
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// valueKind is the iCalendar value type of an output property
+type valueKind int
+
+const (
+	kindText valueKind = iota
+	kindURI
+	kindDateTime
+	kindDate
+	kindDuration
+	kindRecur
+	kindInteger
+	kindUTCOffset
+)
+
+// defaultKinds lists properties whose default value type differs from TEXT,
+// so other types need an explicit VALUE parameter in the text format
+var defaultKinds = map[string]valueKind{
+	"DTSTAMP":       kindDateTime,
+	"CREATED":       kindDateTime,
+	"LAST-MODIFIED": kindDateTime,
+	"DTSTART":       kindDateTime,
+	"DTEND":         kindDateTime,
+	"RECURRENCE-ID": kindDateTime,
+	"RDATE":         kindDateTime,
+	"EXDATE":        kindDateTime,
+	"TRIGGER":       kindDuration,
+	"URL":           kindURI,
+	"RRULE":         kindRecur,
+	"SEQUENCE":      kindInteger,
+	"TZOFFSETFROM":  kindUTCOffset,
+	"TZOFFSETTO":    kindUTCOffset,
+}
+
+// node is a calendar component being built for output. It is rendered either
+// as RFC 5545 text or as RFC 7265 jCal.
+type node struct {
+	name       string
+	properties []property
+	children   []*node
+}
+
+// property is an output property with typed values
+type property struct {
+	name   string
+	kind   valueKind
+	tzid   string // Set for local date-times that reference a VTIMEZONE
+	values []propertyValue
+}
+
+// propertyValue holds one value; which field is used depends on the kind
+type propertyValue struct {
+	text     string
+	time     time.Time
+	floating bool // Local date-time without a UTC designator
+	duration time.Duration
+	integer  int
+}
+
+func newNode(name string) *node {
+	return &node{name: name}
+}
+
+func (n *node) add(p property) {
+	n.properties = append(n.properties, p)
+}
+
+func (n *node) child(name string) *node {
+	c := newNode(name)
+	n.children = append(n.children, c)
+	return c
+}
+
+// text adds a TEXT property, or a list of them if several values are given.
+// Empty values are skipped.
+func (n *node) text(name string, values ...string) {
+	p := property{name: name, kind: kindText}
+	for _, value := range values {
+		if value != "" {
+			p.values = append(p.values, propertyValue{text: value})
+		}
+	}
+	if len(p.values) > 0 {
+		n.add(p)
+	}
+}
+
+// uri adds a URI property, skipping values that are not absolute URIs
+func (n *node) uri(name, value string) {
+	if validURI(value) {
+		n.add(property{name: name, kind: kindURI, values: []propertyValue{{text: value}}})
+	}
+}
+
+// utc adds a DATE-TIME property in UTC
+func (n *node) utc(name string, t time.Time) {
+	n.add(property{name: name, kind: kindDateTime, values: []propertyValue{{time: t.UTC()}}})
+}
+
+// floating adds a DATE-TIME property without zone information
+func (n *node) floating(name string, t time.Time) {
+	n.add(property{name: name, kind: kindDateTime, values: []propertyValue{{time: t, floating: true}}})
+}
+
+// times adds a DATE or DATE-TIME property. Date-times are written in UTC,
+// or as local times referencing loc's VTIMEZONE if loc is set.
+func (n *node) times(name string, allDay bool, loc *time.Location, times ...time.Time) {
+	if len(times) == 0 {
+		return
+	}
+
+	p := property{name: name, kind: kindDateTime}
+	switch {
+	case allDay:
+		p.kind = kindDate
+	case loc != nil:
+		p.tzid = loc.String()
+	}
+
+	for _, t := range times {
+		value := propertyValue{time: t.UTC()}
+		if loc != nil {
+			value.time = t.In(loc)
+			value.floating = !allDay
+		} else if allDay {
+			value.time = t
+		}
+		p.values = append(p.values, value)
+	}
+	n.add(p)
+}
+
+func (n *node) duration(name string, d time.Duration) {
+	n.add(property{name: name, kind: kindDuration, values: []propertyValue{{duration: d}}})
+}
+
+func (n *node) recur(name, rule string) {
+	n.add(property{name: name, kind: kindRecur, values: []propertyValue{{text: rule}}})
+}
+
+func (n *node) integer(name string, value int) {
+	n.add(property{name: name, kind: kindInteger, values: []propertyValue{{integer: value}}})
+}
+
+func (n *node) utcOffset(name string, seconds int) {
+	n.add(property{name: name, kind: kindUTCOffset, values: []propertyValue{{integer: seconds}}})
+}
+
+// writeText renders the node and its children as content lines
+func (n *node) writeText(w *lineWriter) {
+	w.line("BEGIN", n.name)
+	for _, p := range n.properties {
+		w.line(p.textName(), p.textValue())
+	}
+	for _, c := range n.children {
+		c.writeText(w)
+	}
+	w.line("END", n.name)
+}
+
+// textName returns the property name with the parameters the text format needs
+func (p property) textName() string {
+	name := p.name
+	if p.kind != defaultKinds[p.name] {
+		switch p.kind {
+		case kindDate:
+			name += ";VALUE=DATE"
+		case kindDateTime:
+			name += ";VALUE=DATE-TIME"
+		case kindDuration:
+			name += ";VALUE=DURATION"
+		}
+	}
+	if p.tzid != "" {
+		name += ";TZID=" + p.tzid
+	}
+	return name
+}
+
+func (p property) textValue() string {
+	values := make([]string, len(p.values))
+	for i, v := range p.values {
+		switch p.kind {
+		case kindText:
+			values[i] = escapeText(v.text)
+		case kindURI, kindRecur:
+			values[i] = v.text
+		case kindDateTime:
+			if v.floating {
+				values[i] = v.time.Format(localDateTimeFormat)
+			} else {
+				values[i] = formatDateTime(v.time)
+			}
+		case kindDate:
+			values[i] = formatDate(v.time)
+		case kindDuration:
+			values[i] = formatDuration(v.duration)
+		case kindInteger:
+			values[i] = strconv.Itoa(v.integer)
+		case kindUTCOffset:
+			values[i] = formatUTCOffset(v.integer)
+		}
+	}
+	return strings.Join(values, ",")
+}
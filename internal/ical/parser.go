@@ -0,0 +1,687 @@
+package ical
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jacobsee/modcal/internal/models"
+	"github.com/jacobsee/modcal/internal/recurrence"
+)
+
+const (
+	localDateTimeFormat = "20060102T150405"
+
+	// defaultExpansionWindow bounds how far recurring events are expanded
+	// by Parse on either side of the current time
+	defaultExpansionWindow = 365 * 24 * time.Hour
+)
+
+// contentLine is a single unfolded iCalendar property
+type contentLine struct {
+	name   string
+	params map[string]string
+	value  string
+	line   int
+}
+
+// component is a parsed BEGIN/END block with its properties and children
+type component struct {
+	name       string
+	properties []contentLine
+	children   []*component
+}
+
+func (c *component) get(name string) (contentLine, bool) {
+	for _, prop := range c.properties {
+		if prop.name == name {
+			return prop, true
+		}
+	}
+	return contentLine{}, false
+}
+
+// Parse reads an iCalendar stream and converts its VEVENTs to a calendar
+// model. Recurring events are expanded into individual instances within
+// one year of the current time.
+func Parse(r io.Reader) (*models.Calendar, error) {
+	now := time.Now()
+	return ParseRange(r, now.Add(-defaultExpansionWindow), now.Add(defaultExpansionWindow))
+}
+
+// ParseRange is like Parse but expands recurring events only into instances
+// that overlap the [from, to) range. Non-recurring events are always returned.
+func ParseRange(r io.Reader, from, to time.Time) (*models.Calendar, error) {
+	return ParseWith(r, ParseOptions{From: from, To: to})
+}
+
+// ParseOptions controls how ParseWith reads a stream
+type ParseOptions struct {
+	// Recurring events are expanded into instances overlapping [From, To)
+	From, To time.Time
+
+	// Skip is called with the error of each invalid event, which is then
+	// left out so one broken event doesn't lose the whole feed. If nil, an
+	// invalid event fails the stream.
+	Skip func(err error)
+}
+
+// ParseWith is like ParseRange with more control over invalid events
+func ParseWith(r io.Reader, opts ParseOptions) (*models.Calendar, error) {
+	lines, err := readContentLines(r)
+	if err != nil {
+		return nil, err
+	}
+
+	root, err := buildComponents(lines)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &parser{
+		zones: make(map[string]*time.Location),
+		from:  opts.From,
+		to:    opts.To,
+		skip:  opts.Skip,
+	}
+	return p.calendar(root)
+}
+
+// parser holds the state needed while converting one VCALENDAR
+type parser struct {
+	zones map[string]*time.Location
+	from  time.Time
+	to    time.Time
+	skip  func(err error)
+}
+
+// parsedEvent is a VEVENT along with the properties that only matter while
+// assembling the calendar
+type parsedEvent struct {
+	event     models.Event
+	cancelled bool
+}
+
+func (p *parser) calendar(root *component) (*models.Calendar, error) {
+	cal := &models.Calendar{}
+
+	if prop, ok := root.get("X-WR-CALNAME"); ok {
+		cal.Name = unescapeText(prop.value)
+	}
+	if prop, ok := root.get("X-WR-CALDESC"); ok {
+		cal.Description = unescapeText(prop.value)
+	}
+
+	// Time zones must be known before any DTSTART referencing them is read
+	for _, child := range root.children {
+		if child.name == "VTIMEZONE" {
+			p.addTimezone(child)
+		}
+	}
+
+	var parsed []*parsedEvent
+	masters := make(map[string]*models.Event)
+	for _, child := range root.children {
+		if child.name != "VEVENT" {
+			continue
+		}
+		event, err := p.event(child)
+		if err != nil && p.skip != nil {
+			p.skip(err)
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		parsed = append(parsed, event)
+		if event.event.RecurrenceID.IsZero() {
+			masters[event.event.UID] = &event.event
+		}
+	}
+
+	var events []models.Event
+	for _, event := range parsed {
+		if !event.cancelled {
+			events = append(events, event.event)
+			continue
+		}
+		// A cancelled override removes that occurrence from its series. The
+		// master shares its Recurrence with the copy already in events.
+		master, ok := masters[event.event.UID]
+		if !event.event.RecurrenceID.IsZero() && ok && master.IsRecurring() {
+			master.Recurrence.ExDates = append(master.Recurrence.ExDates, event.event.RecurrenceID)
+		}
+	}
+
+	cal.Events = recurrence.Expand(events, p.from, p.to)
+
+	return cal, nil
+}
+
+func (p *parser) event(c *component) (*parsedEvent, error) {
+	parsed := &parsedEvent{}
+	event := &parsed.event
+	var rule string
+	var rdates, exdates []time.Time
+	var duration time.Duration
+	hasDuration := false
+
+	for _, prop := range c.properties {
+		var err error
+		switch prop.name {
+		case "UID":
+			event.UID = prop.value
+		case "SUMMARY":
+			event.Summary = unescapeText(prop.value)
+		case "DESCRIPTION":
+			event.Description = unescapeText(prop.value)
+		case "LOCATION":
+			event.Location = unescapeText(prop.value)
+		case "URL":
+			event.URL = prop.value
+		case "CATEGORIES":
+			event.Categories = append(event.Categories, splitText(prop.value)...)
+		case "CREATED":
+			event.Created, _, err = p.dateTime(prop)
+		case "LAST-MODIFIED":
+			event.LastModified, _, err = p.dateTime(prop)
+		case "SEQUENCE":
+			event.Sequence, err = strconv.Atoi(prop.value)
+		case "STATUS":
+			parsed.cancelled = strings.EqualFold(prop.value, "CANCELLED")
+		case "DTSTART":
+			event.StartTime, event.AllDay, err = p.dateTime(prop)
+			if tzid, ok := prop.params["TZID"]; ok && !event.AllDay {
+				event.TimeZone = p.zoneName(tzid)
+			}
+		case "DTEND":
+			event.EndTime, _, err = p.dateTime(prop)
+		case "DURATION":
+			duration, err = parseDuration(prop.value)
+			hasDuration = true
+		case "RECURRENCE-ID":
+			event.RecurrenceID, _, err = p.dateTime(prop)
+		case "RRULE":
+			rule = prop.value
+		case "RDATE":
+			var dates []time.Time
+			dates, err = p.dateTimeList(prop)
+			rdates = append(rdates, dates...)
+		case "EXDATE":
+			var dates []time.Time
+			dates, err = p.dateTimeList(prop)
+			exdates = append(exdates, dates...)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid %s: %w", prop.line, prop.name, err)
+		}
+	}
+
+	if event.StartTime.IsZero() {
+		return nil, fmt.Errorf("event %q has no DTSTART", event.UID)
+	}
+
+	for _, child := range c.children {
+		if child.name != "VALARM" {
+			continue
+		}
+		alarm, ok, err := p.alarm(child)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			event.Alarms = append(event.Alarms, alarm)
+		}
+	}
+
+	if rule != "" {
+		// Validate now so a broken rule is reported with the feed's line number
+		if _, err := recurrence.ParseRule(rule, event.StartTime.Location()); err != nil {
+			return nil, fmt.Errorf("event %q: invalid RRULE: %w", event.UID, err)
+		}
+	}
+	if rule != "" || len(rdates) > 0 {
+		event.Recurrence = &models.Recurrence{
+			Rule:    rule,
+			RDates:  rdates,
+			ExDates: exdates,
+		}
+	}
+
+	switch {
+	case !event.EndTime.IsZero():
+	case hasDuration:
+		event.EndTime = event.StartTime.Add(duration)
+	case event.AllDay:
+		// A date-only DTSTART without an end lasts one day
+		event.EndTime = event.StartTime.AddDate(0, 0, 1)
+	}
+
+	return parsed, nil
+}
+
+// alarm converts a VALARM. Triggers relative to the event end are not
+// supported and such alarms are skipped.
+func (p *parser) alarm(c *component) (models.Alarm, bool, error) {
+	var alarm models.Alarm
+
+	if prop, ok := c.get("DESCRIPTION"); ok {
+		alarm.Description = unescapeText(prop.value)
+	}
+
+	trigger, ok := c.get("TRIGGER")
+	if !ok || strings.EqualFold(trigger.params["RELATED"], "END") {
+		return alarm, false, nil
+	}
+
+	if strings.EqualFold(trigger.params["VALUE"], "DATE-TIME") {
+		at, _, err := p.dateTime(trigger)
+		if err != nil {
+			return alarm, false, fmt.Errorf("line %d: invalid TRIGGER: %w", trigger.line, err)
+		}
+		alarm.At = at
+		return alarm, true, nil
+	}
+
+	offset, err := parseDuration(trigger.value)
+	if err != nil {
+		return alarm, false, fmt.Errorf("line %d: invalid TRIGGER: %w", trigger.line, err)
+	}
+	alarm.Before = -offset
+
+	return alarm, true, nil
+}
+
+func (p *parser) dateTime(prop contentLine) (time.Time, bool, error) {
+	return p.parseValue(prop.value, prop.params)
+}
+
+func (p *parser) dateTimeList(prop contentLine) ([]time.Time, error) {
+	// PERIOD values are not supported; their start is used instead
+	var times []time.Time
+	for _, value := range strings.Split(prop.value, ",") {
+		value, _, _ = strings.Cut(value, "/")
+		t, _, err := p.parseValue(value, prop.params)
+		if err != nil {
+			return nil, err
+		}
+		times = append(times, t)
+	}
+	return times, nil
+}
+
+func (p *parser) parseValue(value string, params map[string]string) (time.Time, bool, error) {
+	loc := time.Local
+	if tzid, ok := params["TZID"]; ok {
+		loc = p.location(tzid)
+	}
+
+	if strings.EqualFold(params["VALUE"], "DATE") || len(value) == len(dateFormat) {
+		t, err := time.ParseInLocation(dateFormat, value, loc)
+		return t, true, err
+	}
+
+	if strings.HasSuffix(value, "Z") {
+		t, err := time.Parse(dateTimeFormat, value)
+		return t, false, err
+	}
+
+	// Floating times without a TZID are interpreted in the server's local zone
+	t, err := time.ParseInLocation(localDateTimeFormat, value, loc)
+	return t, false, err
+}
+
+// location resolves a TZID, preferring the Go tz database over the
+// feed's own VTIMEZONE definitions
+func (p *parser) location(tzid string) *time.Location {
+	if loc, ok := p.zones[tzid]; ok {
+		return loc
+	}
+	if loc, ok := loadTZID(tzid); ok {
+		p.zones[tzid] = loc
+		return loc
+	}
+	return time.Local
+}
+
+// loadTZID looks a TZID up in the tz database. Some producers prefix the
+// zone name with a path such as "/mozilla.org/20050126_1/Europe/Berlin", so
+// the name after each "/" is tried as well.
+func loadTZID(tzid string) (*time.Location, bool) {
+	name := tzid
+	for name != "" {
+		if loc, err := time.LoadLocation(name); err == nil && name != "Local" {
+			return loc, true
+		}
+		_, rest, ok := strings.Cut(name, "/")
+		if !ok {
+			break
+		}
+		name = rest
+	}
+	return nil, false
+}
+
+// zoneName returns the IANA name for a TZID, or "" if the zone is not part
+// of the Go tz database
+func (p *parser) zoneName(tzid string) string {
+	loc := p.location(tzid)
+	if _, err := models.LoadZone(loc.String()); err != nil || loc == time.Local {
+		return ""
+	}
+	return loc.String()
+}
+
+// addTimezone registers a VTIMEZONE. Zones unknown to the tz database
+// (for example Windows zone names) fall back to a fixed offset taken from
+// the latest STANDARD observance.
+func (p *parser) addTimezone(c *component) {
+	prop, ok := c.get("TZID")
+	if !ok {
+		return
+	}
+	tzid := prop.value
+	if loc, ok := loadTZID(tzid); ok {
+		p.zones[tzid] = loc
+		return
+	}
+
+	var latest, offset string
+	for _, child := range c.children {
+		to, ok := child.get("TZOFFSETTO")
+		if !ok {
+			continue
+		}
+		start, _ := child.get("DTSTART")
+		switch {
+		case child.name == "STANDARD" && start.value >= latest:
+			latest = start.value
+			offset = to.value
+		case offset == "":
+			offset = to.value
+		}
+	}
+
+	if seconds, err := parseUTCOffset(offset); err == nil {
+		p.zones[tzid] = time.FixedZone(tzid, seconds)
+	}
+}
+
+func parseUTCOffset(value string) (int, error) {
+	if len(value) != 5 && len(value) != 7 {
+		return 0, fmt.Errorf("malformed UTC offset %q", value)
+	}
+	sign := 1
+	switch value[0] {
+	case '-':
+		sign = -1
+	case '+':
+	default:
+		return 0, fmt.Errorf("malformed UTC offset %q", value)
+	}
+	hours, err := strconv.Atoi(value[1:3])
+	if err != nil {
+		return 0, err
+	}
+	minutes, err := strconv.Atoi(value[3:5])
+	if err != nil {
+		return 0, err
+	}
+	seconds := 0
+	if len(value) == 7 {
+		if seconds, err = strconv.Atoi(value[5:7]); err != nil {
+			return 0, err
+		}
+	}
+	return sign * (hours*3600 + minutes*60 + seconds), nil
+}
+
+// parseDuration parses an RFC 5545 duration such as "PT1H30M" or "-P1D"
+func parseDuration(value string) (time.Duration, error) {
+	if value == "" {
+		return 0, fmt.Errorf("empty duration")
+	}
+
+	sign := time.Duration(1)
+	switch value[0] {
+	case '-':
+		sign = -1
+		value = value[1:]
+	case '+':
+		value = value[1:]
+	}
+
+	if !strings.HasPrefix(value, "P") {
+		return 0, fmt.Errorf("malformed duration %q", value)
+	}
+	value = value[1:]
+
+	var total time.Duration
+	inTime := false
+	number := ""
+	for _, c := range value {
+		switch {
+		case c >= '0' && c <= '9':
+			number += string(c)
+			continue
+		case c == 'T':
+			inTime = true
+			continue
+		}
+
+		n, err := strconv.Atoi(number)
+		if err != nil {
+			return 0, fmt.Errorf("malformed duration %q", value)
+		}
+		number = ""
+
+		switch {
+		case c == 'W' && !inTime:
+			total += time.Duration(n) * 7 * 24 * time.Hour
+		case c == 'D' && !inTime:
+			total += time.Duration(n) * 24 * time.Hour
+		case c == 'H' && inTime:
+			total += time.Duration(n) * time.Hour
+		case c == 'M' && inTime:
+			total += time.Duration(n) * time.Minute
+		case c == 'S' && inTime:
+			total += time.Duration(n) * time.Second
+		default:
+			return 0, fmt.Errorf("malformed duration %q", value)
+		}
+	}
+	if number != "" {
+		return 0, fmt.Errorf("malformed duration %q", value)
+	}
+
+	return sign * total, nil
+}
+
+// buildComponents nests content lines into their BEGIN/END components and
+// returns the outermost VCALENDAR
+func buildComponents(lines []contentLine) (*component, error) {
+	var root *component
+	var stack []*component
+
+	for _, line := range lines {
+		switch line.name {
+		case "BEGIN":
+			c := &component{name: strings.ToUpper(line.value)}
+			if len(stack) > 0 {
+				parent := stack[len(stack)-1]
+				parent.children = append(parent.children, c)
+			} else if root == nil {
+				root = c
+			}
+			stack = append(stack, c)
+		case "END":
+			name := strings.ToUpper(line.value)
+			if len(stack) == 0 || stack[len(stack)-1].name != name {
+				return nil, fmt.Errorf("line %d: unexpected END:%s", line.line, line.value)
+			}
+			stack = stack[:len(stack)-1]
+		default:
+			if len(stack) == 0 {
+				return nil, fmt.Errorf("line %d: property %s outside of a component", line.line, line.name)
+			}
+			current := stack[len(stack)-1]
+			current.properties = append(current.properties, line)
+		}
+	}
+
+	if len(stack) != 0 {
+		return nil, fmt.Errorf("unterminated %s component", stack[len(stack)-1].name)
+	}
+	if root == nil || root.name != "VCALENDAR" {
+		return nil, fmt.Errorf("no VCALENDAR component found")
+	}
+
+	return root, nil
+}
+
+// readContentLines unfolds and splits an iCalendar stream into content lines
+func readContentLines(r io.Reader) ([]contentLine, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+
+	type rawLine struct {
+		text string
+		line int
+	}
+
+	var unfolded []rawLine
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		raw := strings.TrimRight(scanner.Text(), "\r")
+		if lineNumber == 1 {
+			raw = strings.TrimPrefix(raw, "\ufeff")
+		}
+		if raw == "" {
+			continue
+		}
+		if (raw[0] == ' ' || raw[0] == '\t') && len(unfolded) > 0 {
+			unfolded[len(unfolded)-1].text += raw[1:]
+			continue
+		}
+		unfolded = append(unfolded, rawLine{text: raw, line: lineNumber})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	lines := make([]contentLine, 0, len(unfolded))
+	for _, raw := range unfolded {
+		line, err := parseContentLine(raw.text)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", raw.line, err)
+		}
+		line.line = raw.line
+		lines = append(lines, line)
+	}
+
+	return lines, nil
+}
+
+func parseContentLine(raw string) (contentLine, error) {
+	line := contentLine{params: make(map[string]string)}
+
+	// The value starts at the first colon that is not inside a quoted
+	// parameter value
+	inQuotes := false
+	split := -1
+	for i, c := range raw {
+		if c == '"' {
+			inQuotes = !inQuotes
+		} else if c == ':' && !inQuotes {
+			split = i
+			break
+		}
+	}
+	if split == -1 {
+		return line, fmt.Errorf("malformed content line %q", raw)
+	}
+
+	head := raw[:split]
+	line.value = raw[split+1:]
+
+	parts := splitParams(head)
+	line.name = strings.ToUpper(parts[0])
+	for _, param := range parts[1:] {
+		key, value, ok := strings.Cut(param, "=")
+		if !ok {
+			continue
+		}
+		line.params[strings.ToUpper(key)] = strings.Trim(value, "\"")
+	}
+
+	return line, nil
+}
+
+func splitParams(head string) []string {
+	var parts []string
+	inQuotes := false
+	start := 0
+	for i, c := range head {
+		switch {
+		case c == '"':
+			inQuotes = !inQuotes
+		case c == ';' && !inQuotes:
+			parts = append(parts, head[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, head[start:])
+}
+
+// splitText splits a comma separated list of text values, honouring escapes
+func splitText(value string) []string {
+	var values []string
+	var current strings.Builder
+	escaped := false
+	for _, c := range value {
+		switch {
+		case escaped:
+			current.WriteRune('\\')
+			current.WriteRune(c)
+			escaped = false
+		case c == '\\':
+			escaped = true
+		case c == ',':
+			values = append(values, unescapeText(current.String()))
+			current.Reset()
+		default:
+			current.WriteRune(c)
+		}
+	}
+	if current.Len() > 0 {
+		values = append(values, unescapeText(current.String()))
+	}
+	return values
+}
+
+func unescapeText(text string) string {
+	var builder strings.Builder
+	escaped := false
+	for _, c := range text {
+		if escaped {
+			switch c {
+			case 'n', 'N':
+				builder.WriteRune('\n')
+			default:
+				builder.WriteRune(c)
+			}
+			escaped = false
+			continue
+		}
+		if c == '\\' {
+			escaped = true
+			continue
+		}
+		builder.WriteRune(c)
+	}
+	return builder.String()
+}
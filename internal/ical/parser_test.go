@@ -0,0 +1,308 @@
+package ical
+
+import (
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/jacobsee/modcal/internal/models"
+	"github.com/jacobsee/modcal/internal/recurrence"
+)
+
+var (
+	rangeFrom = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	rangeTo   = time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+)
+
+func mustZone(t *testing.T, name string) *time.Location {
+	t.Helper()
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		t.Fatalf("LoadLocation(%q): %v", name, err)
+	}
+	return loc
+}
+
+func parseText(t *testing.T, text string) ([]models.Event, []error) {
+	t.Helper()
+	var skipped []error
+	cal, err := ParseWith(strings.NewReader(text), ParseOptions{
+		From: rangeFrom,
+		To:   rangeTo,
+		Skip: func(err error) { skipped = append(skipped, err) },
+	})
+	if err != nil {
+		t.Fatalf("ParseWith: %v", err)
+	}
+	return cal.Events, skipped
+}
+
+func TestFormatParseRoundTrip(t *testing.T) {
+	newYork := mustZone(t, "America/New_York")
+	start := time.Date(2024, 3, 5, 20, 0, 0, 0, time.UTC)
+	long := strings.Repeat("日本語のタイトル ", 20)
+
+	tests := []struct {
+		name   string
+		events []models.Event
+		check  func(t *testing.T, got []models.Event)
+	}{
+		{
+			name: "folding with multi-byte characters",
+			events: []models.Event{{
+				UID: "fold", Summary: long, Description: strings.Repeat("a", 200),
+				StartTime: start, EndTime: start.Add(time.Hour),
+			}},
+			check: func(t *testing.T, got []models.Event) {
+				if got[0].Summary != long {
+					t.Errorf("Summary = %q, want %q", got[0].Summary, long)
+				}
+				if got[0].Description != strings.Repeat("a", 200) {
+					t.Errorf("Description = %q", got[0].Description)
+				}
+			},
+		},
+		{
+			name: "escaped text",
+			events: []models.Event{{
+				UID: "escape", Summary: `One, two; three\four`, Description: "Line 1\nLine 2, with; all",
+				Location: "Room 1, Floor 2", Categories: []string{"Drama, Comedy", "Anime"},
+				StartTime: start, EndTime: start.Add(time.Hour),
+			}},
+			check: func(t *testing.T, got []models.Event) {
+				e := got[0]
+				if e.Summary != `One, two; three\four` {
+					t.Errorf("Summary = %q", e.Summary)
+				}
+				if e.Description != "Line 1\nLine 2, with; all" {
+					t.Errorf("Description = %q", e.Description)
+				}
+				if e.Location != "Room 1, Floor 2" {
+					t.Errorf("Location = %q", e.Location)
+				}
+				if len(e.Categories) != 2 || e.Categories[0] != "Drama, Comedy" || e.Categories[1] != "Anime" {
+					t.Errorf("Categories = %q", e.Categories)
+				}
+			},
+		},
+		{
+			name: "TZID",
+			events: []models.Event{{
+				UID: "zoned", Summary: "Zoned", TimeZone: "America/New_York",
+				StartTime: time.Date(2024, 7, 1, 21, 0, 0, 0, newYork),
+				EndTime:   time.Date(2024, 7, 1, 22, 0, 0, 0, newYork),
+			}},
+			check: func(t *testing.T, got []models.Event) {
+				e := got[0]
+				if e.TimeZone != "America/New_York" {
+					t.Errorf("TimeZone = %q", e.TimeZone)
+				}
+				if want := time.Date(2024, 7, 1, 21, 0, 0, 0, newYork); !e.StartTime.Equal(want) {
+					t.Errorf("StartTime = %v, want %v", e.StartTime, want)
+				}
+				if want := time.Date(2024, 7, 1, 22, 0, 0, 0, newYork); !e.EndTime.Equal(want) {
+					t.Errorf("EndTime = %v, want %v", e.EndTime, want)
+				}
+			},
+		},
+		{
+			name: "VALUE=DATE",
+			events: []models.Event{{
+				UID: "allday", Summary: "All day", AllDay: true,
+				StartTime: time.Date(2024, 5, 10, 0, 0, 0, 0, time.Local),
+				EndTime:   time.Date(2024, 5, 11, 0, 0, 0, 0, time.Local),
+			}},
+			check: func(t *testing.T, got []models.Event) {
+				e := got[0]
+				if !e.AllDay {
+					t.Errorf("AllDay = false")
+				}
+				if s := e.StartTime.Format("20060102"); s != "20240510" {
+					t.Errorf("StartTime = %s, want 20240510", s)
+				}
+				if s := e.EndTime.Format("20060102"); s != "20240511" {
+					t.Errorf("EndTime = %s, want 20240511", s)
+				}
+			},
+		},
+		{
+			name: "RRULE, EXDATE and RECURRENCE-ID",
+			events: []models.Event{
+				{
+					UID: "series", Summary: "Weekly",
+					StartTime: start, EndTime: start.Add(time.Hour),
+					Recurrence: &models.Recurrence{
+						Rule:    "FREQ=WEEKLY;COUNT=4",
+						ExDates: []time.Time{start.AddDate(0, 0, 7)},
+					},
+				},
+				{
+					UID: "series", Summary: "Moved", RecurrenceID: start.AddDate(0, 0, 14),
+					StartTime: start.AddDate(0, 0, 14).Add(2 * time.Hour),
+					EndTime:   start.AddDate(0, 0, 14).Add(3 * time.Hour),
+				},
+			},
+			check: func(t *testing.T, got []models.Event) {
+				want := []struct {
+					original time.Time
+					start    time.Time
+					summary  string
+				}{
+					{start, start, "Weekly"},
+					{start.AddDate(0, 0, 14), start.AddDate(0, 0, 14).Add(2 * time.Hour), "Moved"},
+					{start.AddDate(0, 0, 21), start.AddDate(0, 0, 21), "Weekly"},
+				}
+				if len(got) != len(want) {
+					t.Fatalf("got %d occurrences, want %d: %+v", len(got), len(want), got)
+				}
+				for i, w := range want {
+					if uid := recurrence.InstanceUID("series", w.original); got[i].UID != uid {
+						t.Errorf("occurrence %d: UID = %q, want %q", i, got[i].UID, uid)
+					}
+					if !got[i].StartTime.Equal(w.start) {
+						t.Errorf("occurrence %d: StartTime = %v, want %v", i, got[i].StartTime, w.start)
+					}
+					if got[i].Summary != w.summary {
+						t.Errorf("occurrence %d: Summary = %q, want %q", i, got[i].Summary, w.summary)
+					}
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			text := Format(&models.Calendar{Name: "Test", Events: tt.events})
+			for _, line := range strings.Split(strings.TrimSuffix(text, "\r\n"), "\r\n") {
+				if len(line) > maxLineOctets {
+					t.Errorf("line is %d octets: %q", len(line), line)
+				}
+				if !utf8.ValidString(line) {
+					t.Errorf("line splits a character: %q", line)
+				}
+			}
+
+			got, skipped := parseText(t, text)
+			if len(skipped) > 0 {
+				t.Fatalf("skipped events: %v", skipped)
+			}
+			if len(got) == 0 {
+				t.Fatalf("no events parsed from:\n%s", text)
+			}
+			tt.check(t, got)
+		})
+	}
+}
+
+func TestParseDuration(t *testing.T) {
+	text := "BEGIN:VCALENDAR\r\n" +
+		"BEGIN:VEVENT\r\n" +
+		"UID:duration\r\n" +
+		"DTSTART:20240305T200000Z\r\n" +
+		"DURATION:PT1H30M\r\n" +
+		"SUMMARY:Duration\r\n" +
+		"END:VEVENT\r\n" +
+		"END:VCALENDAR\r\n"
+
+	got, _ := parseText(t, text)
+	if len(got) != 1 {
+		t.Fatalf("got %d events, want 1", len(got))
+	}
+	want := time.Date(2024, 3, 5, 21, 30, 0, 0, time.UTC)
+	if !got[0].EndTime.Equal(want) {
+		t.Errorf("EndTime = %v, want %v", got[0].EndTime, want)
+	}
+
+	// The formatter writes the end as DTEND, which must parse to the same time
+	again, _ := parseText(t, Format(&models.Calendar{Events: got}))
+	if len(again) != 1 || !again[0].EndTime.Equal(want) {
+		t.Errorf("after round trip: %+v", again)
+	}
+}
+
+func TestParseSkipsInvalidEvents(t *testing.T) {
+	text := "BEGIN:VCALENDAR\r\n" +
+		"BEGIN:VEVENT\r\n" +
+		"UID:good\r\n" +
+		"DTSTART:20240305T200000Z\r\n" +
+		"END:VEVENT\r\n" +
+		"BEGIN:VEVENT\r\n" +
+		"UID:no-start\r\n" +
+		"SUMMARY:No start\r\n" +
+		"END:VEVENT\r\n" +
+		"BEGIN:VEVENT\r\n" +
+		"UID:bad-rule\r\n" +
+		"DTSTART:20240305T200000Z\r\n" +
+		"RRULE:FREQ=HOURLY\r\n" +
+		"END:VEVENT\r\n" +
+		"BEGIN:VEVENT\r\n" +
+		"UID:bad-date\r\n" +
+		"DTSTART:2024-03-05\r\n" +
+		"END:VEVENT\r\n" +
+		"END:VCALENDAR\r\n"
+
+	got, skipped := parseText(t, text)
+	if len(got) != 1 || got[0].UID != "good" {
+		t.Errorf("events = %+v, want only good", got)
+	}
+	if len(skipped) != 3 {
+		t.Errorf("skipped = %v, want 3 errors", skipped)
+	}
+
+	// Without Skip, the first invalid event fails the stream
+	if _, err := ParseRange(strings.NewReader(text), rangeFrom, rangeTo); err == nil {
+		t.Error("ParseRange succeeded with invalid events")
+	}
+}
+
+func TestParseTZID(t *testing.T) {
+	berlin := mustZone(t, "Europe/Berlin")
+	tests := []struct {
+		name string
+		text string
+	}{
+		{
+			name: "IANA name",
+			text: "DTSTART;TZID=Europe/Berlin:20240705T200000\r\n",
+		},
+		{
+			name: "path prefix",
+			text: "DTSTART;TZID=/mozilla.org/20050126_1/Europe/Berlin:20240705T200000\r\n",
+		},
+		{
+			name: "path prefix with VTIMEZONE",
+			text: "DTSTART;TZID=/citadel.org/20190914_1/Europe/Berlin:20240705T200000\r\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			text := "BEGIN:VCALENDAR\r\n" +
+				"BEGIN:VTIMEZONE\r\n" +
+				"TZID:/citadel.org/20190914_1/Europe/Berlin\r\n" +
+				"BEGIN:STANDARD\r\n" +
+				"DTSTART:19701025T030000\r\n" +
+				"TZOFFSETFROM:+0200\r\n" +
+				"TZOFFSETTO:+0100\r\n" +
+				"END:STANDARD\r\n" +
+				"END:VTIMEZONE\r\n" +
+				"BEGIN:VEVENT\r\n" +
+				"UID:zoned\r\n" +
+				tt.text +
+				"END:VEVENT\r\n" +
+				"END:VCALENDAR\r\n"
+
+			got, _ := parseText(t, text)
+			if len(got) != 1 {
+				t.Fatalf("got %d events, want 1", len(got))
+			}
+			if want := time.Date(2024, 7, 5, 20, 0, 0, 0, berlin); !got[0].StartTime.Equal(want) {
+				t.Errorf("StartTime = %v, want %v", got[0].StartTime, want)
+			}
+			if got[0].TimeZone != "Europe/Berlin" {
+				t.Errorf("TimeZone = %q, want Europe/Berlin", got[0].TimeZone)
+			}
+		})
+	}
+}
@@ -0,0 +1,202 @@
+package ical
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jacobsee/modcal/internal/models"
+)
+
+// zoneUsage records the zones referenced by a calendar and the time span
+// each of them has to cover
+type zoneUsage struct {
+	names []string
+	zones map[string]*time.Location
+	from  map[string]time.Time
+	to    map[string]time.Time
+}
+
+func collectZones(events []models.Event) *zoneUsage {
+	usage := &zoneUsage{
+		zones: make(map[string]*time.Location),
+		from:  make(map[string]time.Time),
+		to:    make(map[string]time.Time),
+	}
+
+	for i := range events {
+		event := &events[i]
+		loc := event.Zone()
+		if loc == nil || event.AllDay {
+			continue
+		}
+		name := loc.String()
+		if _, ok := usage.zones[name]; !ok {
+			usage.names = append(usage.names, name)
+			usage.zones[name] = loc
+			usage.from[name] = event.StartTime
+			usage.to[name] = event.StartTime
+		}
+		for _, t := range []time.Time{event.StartTime, event.EndTime, event.RecurrenceID} {
+			if t.IsZero() {
+				continue
+			}
+			if t.Before(usage.from[name]) {
+				usage.from[name] = t
+			}
+			if t.After(usage.to[name]) {
+				usage.to[name] = t
+			}
+		}
+	}
+
+	return usage
+}
+
+// zoneTransition is one onset of a STANDARD or DAYLIGHT observance
+type zoneTransition struct {
+	at         time.Time
+	offsetFrom int
+	offsetTo   int
+	name       string
+	dst        bool
+}
+
+// buildTimezone adds a VTIMEZONE for loc covering [from, to]. Historical
+// transitions are listed explicitly; if the zone still observes DST, its
+// latest STANDARD and DAYLIGHT onsets become yearly rules so recurring
+// events keep the right offset beyond the covered range.
+func buildTimezone(cal *node, name string, loc *time.Location, from, to time.Time) {
+	transitions := zoneTransitions(loc, from, to)
+
+	tz := cal.child("VTIMEZONE")
+	tz.text("TZID", name)
+
+	lastIndex := map[bool]int{true: -1, false: -1}
+	for i, tr := range transitions {
+		lastIndex[tr.dst] = i
+	}
+
+	for i, tr := range transitions {
+		var rule string
+		if i > 0 && lastIndex[true] >= 0 && lastIndex[false] >= 0 && i == lastIndex[tr.dst] {
+			rule = yearlyRule(tr, transitions[:i])
+		}
+		buildObservance(tz, tr, rule)
+	}
+}
+
+func buildObservance(tz *node, tr zoneTransition, rule string) {
+	kind := "STANDARD"
+	if tr.dst {
+		kind = "DAYLIGHT"
+	}
+
+	observance := tz.child(kind)
+	// Onsets are expressed in local time as observed before the transition
+	observance.floating("DTSTART", localOnset(tr))
+	observance.utcOffset("TZOFFSETFROM", tr.offsetFrom)
+	observance.utcOffset("TZOFFSETTO", tr.offsetTo)
+	if rule != "" {
+		observance.recur("RRULE", rule)
+	}
+	if tr.name != "" && !strings.HasPrefix(tr.name, "+") && !strings.HasPrefix(tr.name, "-") {
+		observance.text("TZNAME", tr.name)
+	}
+}
+
+// zoneTransitions lists the observance in effect at from followed by every
+// transition up to one year after to
+func zoneTransitions(loc *time.Location, from, to time.Time) []zoneTransition {
+	start := time.Date(from.In(loc).Year(), time.January, 1, 0, 0, 0, 0, loc)
+	limit := time.Date(to.In(loc).Year()+1, time.December, 31, 0, 0, 0, 0, loc)
+
+	name, offset := start.Zone()
+	periodStart, _ := start.ZoneBounds()
+	first := zoneTransition{
+		at:         time.Date(1970, time.January, 1, 0, 0, 0, 0, time.UTC).Add(-time.Duration(offset) * time.Second),
+		offsetFrom: offset,
+		offsetTo:   offset,
+		name:       name,
+		dst:        start.IsDST(),
+	}
+	if !periodStart.IsZero() {
+		_, before := periodStart.Add(-time.Second).Zone()
+		first.at = periodStart
+		first.offsetFrom = before
+	}
+	transitions := []zoneTransition{first}
+
+	current := start
+	for {
+		_, end := current.ZoneBounds()
+		if end.IsZero() || end.After(limit) {
+			break
+		}
+		_, before := current.Zone()
+		next := end.In(loc)
+		name, after := next.Zone()
+		transitions = append(transitions, zoneTransition{
+			at:         end,
+			offsetFrom: before,
+			offsetTo:   after,
+			name:       name,
+			dst:        next.IsDST(),
+		})
+		current = next
+	}
+
+	return transitions
+}
+
+// yearlyRule derives an RRULE such as "FREQ=YEARLY;BYMONTH=3;BYDAY=2SU" from
+// a transition, provided the same rule also produced the previous year's
+// transition of that kind
+func yearlyRule(tr zoneTransition, earlier []zoneTransition) string {
+	onset := localOnset(tr)
+	rule := byDayRule(onset)
+
+	for i := len(earlier) - 1; i >= 0; i-- {
+		prev := earlier[i]
+		if prev.dst != tr.dst {
+			continue
+		}
+		prevOnset := localOnset(prev)
+		if prevOnset.Year() != onset.Year()-1 || byDayRule(prevOnset) != rule ||
+			prevOnset.Hour() != onset.Hour() || prevOnset.Minute() != onset.Minute() ||
+			prev.offsetFrom != tr.offsetFrom || prev.offsetTo != tr.offsetTo {
+			return ""
+		}
+		return rule
+	}
+
+	return ""
+}
+
+func localOnset(tr zoneTransition) time.Time {
+	return tr.at.UTC().Add(time.Duration(tr.offsetFrom) * time.Second)
+}
+
+func byDayRule(onset time.Time) string {
+	daysInMonth := time.Date(onset.Year(), onset.Month()+1, 0, 0, 0, 0, 0, time.UTC).Day()
+	ordinal := (onset.Day()-1)/7 + 1
+	if onset.Day()+7 > daysInMonth {
+		ordinal = -1
+	}
+	weekday := strings.ToUpper(onset.Weekday().String()[:2])
+	return fmt.Sprintf("FREQ=YEARLY;BYMONTH=%d;BYDAY=%d%s", int(onset.Month()), ordinal, weekday)
+}
+
+func formatUTCOffset(seconds int) string {
+	sign := "+"
+	if seconds < 0 {
+		sign = "-"
+		seconds = -seconds
+	}
+	hours := seconds / 3600
+	minutes := (seconds % 3600) / 60
+	if rest := seconds % 60; rest != 0 {
+		return fmt.Sprintf("%s%02d%02d%02d", sign, hours, minutes, rest)
+	}
+	return fmt.Sprintf("%s%02d%02d", sign, hours, minutes)
+}
@@ -0,0 +1,128 @@
+package jsoncal
+
+import (
+	"encoding/json"
+	"sort"
+	"time"
+
+	"github.com/jacobsee/modcal/internal/models"
+	"github.com/jacobsee/modcal/internal/recurrence"
+)
+
+const (
+	// Recurring series are expanded into concrete events within this window
+	expandBack    = 30 * 24 * time.Hour
+	expandForward = 365 * 24 * time.Hour
+)
+
+// Calendar is the modcal JSON representation of a calendar
+type Calendar struct {
+	Name        string  `json:"name"`
+	Description string  `json:"description,omitempty"`
+	TimeZone    string  `json:"timeZone,omitempty"`
+	Events      []Event `json:"events"`
+}
+
+// Event is the modcal JSON representation of an event. Start and End are
+// RFC 3339 timestamps, or plain dates (YYYY-MM-DD) for all-day events.
+type Event struct {
+	UID          string     `json:"uid"`
+	Summary      string     `json:"summary"`
+	Description  string     `json:"description,omitempty"`
+	Location     string     `json:"location,omitempty"`
+	Start        string     `json:"start"`
+	End          string     `json:"end,omitempty"`
+	AllDay       bool       `json:"allDay"`
+	TimeZone     string     `json:"timeZone,omitempty"`
+	URL          string     `json:"url,omitempty"`
+	Categories   []string   `json:"categories,omitempty"`
+	Show         string     `json:"show,omitempty"`
+	Alarms       []Alarm    `json:"alarms,omitempty"`
+	Created      *time.Time `json:"created,omitempty"`
+	LastModified *time.Time `json:"lastModified,omitempty"`
+	Sequence     int        `json:"sequence"`
+}
+
+// Alarm is the modcal JSON representation of a reminder. At is always set;
+// BeforeMinutes is set for reminders relative to the event start.
+type Alarm struct {
+	Description   string    `json:"description,omitempty"`
+	At            time.Time `json:"at"`
+	BeforeMinutes *float64  `json:"beforeMinutes,omitempty"`
+}
+
+// Format converts a calendar model to modcal's JSON schema. Recurring series
+// are expanded into individual events within the calendar's range, or a
+// default window around now, and all events are sorted by start.
+func Format(cal *models.Calendar) ([]byte, error) {
+	now := time.Now()
+	from, to := now.Add(-expandBack), now.Add(expandForward)
+	if !cal.From.IsZero() {
+		from, to = cal.From, cal.To
+	}
+	events := recurrence.Expand(cal.Events, from, to)
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].StartTime.Before(events[j].StartTime)
+	})
+
+	out := Calendar{
+		Name:        cal.Name,
+		Description: cal.Description,
+		TimeZone:    cal.TimeZone,
+		Events:      make([]Event, 0, len(events)),
+	}
+	for i := range events {
+		out.Events = append(out.Events, convertEvent(&events[i]))
+	}
+
+	return json.MarshalIndent(out, "", "  ")
+}
+
+func convertEvent(event *models.Event) Event {
+	out := Event{
+		UID:         event.UID,
+		Summary:     event.Summary,
+		Description: event.Description,
+		Location:    event.Location,
+		Start:       formatTime(event, event.StartTime),
+		AllDay:      event.AllDay,
+		TimeZone:    event.TimeZone,
+		URL:         event.URL,
+		Categories:  event.Categories,
+		Show:        event.Show,
+		Sequence:    event.Sequence,
+	}
+	if !event.EndTime.IsZero() {
+		out.End = formatTime(event, event.EndTime)
+	}
+	if !event.Created.IsZero() {
+		created := event.Created.UTC()
+		out.Created = &created
+	}
+	if !event.LastModified.IsZero() {
+		modified := event.LastModified.UTC()
+		out.LastModified = &modified
+	}
+
+	for _, alarm := range event.Alarms {
+		converted := Alarm{Description: alarm.Description, At: alarm.At}
+		if alarm.At.IsZero() {
+			converted.At = event.StartTime.Add(-alarm.Before)
+			minutes := alarm.Before.Minutes()
+			converted.BeforeMinutes = &minutes
+		}
+		out.Alarms = append(out.Alarms, converted)
+	}
+
+	return out
+}
+
+func formatTime(event *models.Event, t time.Time) string {
+	if loc := event.Zone(); loc != nil {
+		t = t.In(loc)
+	}
+	if event.AllDay {
+		return t.Format("2006-01-02")
+	}
+	return t.Format(time.RFC3339)
+}
@@ -1,8 +1,16 @@
 package models
 
+import "time"
+
 // Calendar represents an aggregated calendar with events from multiple plugins
 type Calendar struct {
 	Name        string
 	Description string
+	TimeZone    string // IANA zone the calendar is displayed in, if any
 	Events      []Event
+
+	// From and To are set when the events were limited to a time range, in
+	// which case recurring series have been expanded within it
+	From time.Time
+	To   time.Time
 }
@@ -4,13 +4,68 @@ import "time"
 
 // Event represents a calendar event from a plugin
 type Event struct {
-	UID         string
-	Summary     string
-	Description string
-	Location    string
-	StartTime   time.Time
-	EndTime     time.Time
-	AllDay      bool
-	URL         string
-	Categories  []string
+	UID          string
+	Summary      string
+	Description  string
+	Location     string
+	StartTime    time.Time
+	EndTime      time.Time
+	AllDay       bool
+	TimeZone     string // IANA zone the event is scheduled in, e.g. "Asia/Tokyo"
+	URL          string
+	Categories   []string
+	Show         string    // Name of the show the event is an episode of, if any
+	Source       string    // ID of the plugin instance the event came from
+	Created      time.Time // When the event was first seen
+	LastModified time.Time // When the event content last changed
+	Sequence     int       // Revision number, incremented on every change
+
+	// ExternalIDs identifies what the event is about in other databases,
+	// e.g. "mal" or "imdb" to the show's ID there
+	ExternalIDs map[string]string
+
+	// Fields holds the raw data the summary and description were rendered
+	// from, e.g. "show" or "episode", see plugin.Templates
+	Fields map[string]interface{}
+
+	// Alarms are reminders shown by calendar clients before the event
+	Alarms []Alarm
+
+	// Recurrence makes this event the master of a recurring series
+	Recurrence *Recurrence
+	// RecurrenceID marks this event as an override of the occurrence of the
+	// series with the same UID that originally started at this time
+	RecurrenceID time.Time
+}
+
+// Alarm is a reminder attached to an event
+type Alarm struct {
+	Description string        // Reminder text, defaults to the event summary
+	Before      time.Duration // Trigger relative to the event start
+	At          time.Time     // Absolute trigger, takes precedence over Before
+}
+
+// Recurrence describes how an event repeats
+type Recurrence struct {
+	Rule    string      // RRULE value, e.g. "FREQ=WEEKLY;BYDAY=TH"
+	RDates  []time.Time // Additional occurrences
+	ExDates []time.Time // Occurrences removed from the series
+}
+
+// Zone returns the location named by TimeZone, or nil if the event has no
+// zone or the zone is unknown
+func (e *Event) Zone() *time.Location {
+	if e.TimeZone == "" {
+		return nil
+	}
+	loc, err := LoadZone(e.TimeZone)
+	if err != nil {
+		return nil
+	}
+	return loc
+}
+
+// IsRecurring reports whether the event is the master of a recurring series
+func (e *Event) IsRecurring() bool {
+	return e.Recurrence != nil && (e.Recurrence.Rule != "" || len(e.Recurrence.RDates) > 0)
 }
@@ -0,0 +1,22 @@
+package models
+
+import (
+	"sync"
+	"time"
+)
+
+var zoneCache sync.Map
+
+// LoadZone is time.LoadLocation with caching, since zones are looked up for
+// every event on every request
+func LoadZone(name string) (*time.Location, error) {
+	if loc, ok := zoneCache.Load(name); ok {
+		return loc.(*time.Location), nil
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, err
+	}
+	zoneCache.Store(name, loc)
+	return loc, nil
+}
@@ -0,0 +1,74 @@
+package plugin
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Authorizer is implemented by plugin instances whose OAuth tokens can be
+// obtained interactively, so users don't have to copy tokens into the config
+type Authorizer interface {
+	// StartAuth begins authorizing the instance. redirectURL is where flows
+	// that redirect the browser should send it back to.
+	StartAuth(ctx context.Context, redirectURL string) (*AuthFlow, error)
+}
+
+// AuthFlow is an authorization in progress
+type AuthFlow struct {
+	URL      string    // Page the user opens to authorize
+	UserCode string    // Code the user enters on URL, for device flows
+	State    string    // Passed back with the code by flows that redirect
+	Expires  time.Time // Zero if unknown
+
+	// Finish completes the flow and stores the token. Device flows are
+	// finished with an empty code and block until the user has authorized;
+	// other flows are finished with the code the provider returned.
+	Finish func(ctx context.Context, code string) error
+}
+
+// Device reports whether the flow completes without a code from the user
+func (f *AuthFlow) Device() bool {
+	return f.UserCode != ""
+}
+
+// Code extracts the authorization code from what the user pasted, which may
+// be the code itself or the whole URL the provider redirected to. A state in
+// the URL must match the flow's.
+func (f *AuthFlow) Code(input string) (string, error) {
+	input = strings.TrimSpace(input)
+	u, err := url.Parse(input)
+	if err != nil || u.Scheme == "" {
+		if input == "" {
+			return "", fmt.Errorf("authorization code is required")
+		}
+		return input, nil
+	}
+
+	query := u.Query()
+	if msg := query.Get("error"); msg != "" {
+		return "", fmt.Errorf("authorization failed: %s", msg)
+	}
+	if state := query.Get("state"); state != "" && state != f.State {
+		return "", fmt.Errorf("authorization state does not match, start again")
+	}
+	code := query.Get("code")
+	if code == "" {
+		return "", fmt.Errorf("URL has no authorization code")
+	}
+	return code, nil
+}
+
+// RandomString returns a URL-safe random string for OAuth states and PKCE
+// verifiers
+func RandomString() string {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		panic(fmt.Sprintf("failed to generate random bytes: %v", err))
+	}
+	return base64.RawURLEncoding.EncodeToString(b)
+}
@@ -0,0 +1,83 @@
+package plugin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/jacobsee/modcal/internal/metrics"
+	"github.com/jacobsee/modcal/internal/tokens"
+)
+
+var upstreamRequests = metrics.Default.NewCounterVec(
+	"modcal_upstream_requests_total",
+	"Requests plugins made to upstream APIs, by plugin type, host and status code.",
+	"plugin", "host", "code",
+)
+
+// HTTPError is returned by plugins when an API responds with an unexpected
+// status code
+type HTTPError struct {
+	StatusCode int
+	Body       string
+}
+
+func (e *HTTPError) Error() string {
+	return fmt.Sprintf("API returned status %d: %s", e.StatusCode, e.Body)
+}
+
+// WithToken calls fn with the current access token of src. If the API
+// rejects it with 401 and the token can be refreshed, fn is called once more
+// with the refreshed token; otherwise the error says to authorize again.
+func WithToken(ctx context.Context, src *tokens.Source, fn func(accessToken string) error) error {
+	accessToken, err := src.AccessToken(ctx)
+	if err != nil {
+		return err
+	}
+
+	err = fn(accessToken)
+	var httpErr *HTTPError
+	if !errors.As(err, &httpErr) || httpErr.StatusCode != http.StatusUnauthorized {
+		return err
+	}
+	if !src.CanRefresh() {
+		return fmt.Errorf("%w (access token cannot be refreshed, authorize again)", err)
+	}
+
+	accessToken, refreshErr := src.Refresh(ctx, accessToken)
+	if refreshErr != nil {
+		return fmt.Errorf("%w (%v)", err, refreshErr)
+	}
+	return fn(accessToken)
+}
+
+// NewHTTPClient returns the HTTP client plugins of the given type use to
+// call their APIs. Its requests are counted in the upstream request metrics.
+func NewHTTPClient(pluginType string) *http.Client {
+	return &http.Client{
+		Timeout: 30 * time.Second,
+		Transport: &countingTransport{
+			pluginType: pluginType,
+			next:       http.DefaultTransport,
+		},
+	}
+}
+
+// countingTransport counts requests by their outcome
+type countingTransport struct {
+	pluginType string
+	next       http.RoundTripper
+}
+
+func (t *countingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.next.RoundTrip(req)
+	code := "error"
+	if err == nil {
+		code = strconv.Itoa(resp.StatusCode)
+	}
+	upstreamRequests.Inc(t.pluginType, req.URL.Host, code)
+	return resp, err
+}
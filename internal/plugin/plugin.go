@@ -2,8 +2,10 @@ package plugin
 
 import (
 	"context"
+	"log/slog"
 
 	"github.com/jacobsee/modcal/internal/models"
+	"github.com/jacobsee/modcal/internal/tokens"
 )
 
 // Plugin is the interface that all calendar plugins must implement
@@ -11,9 +13,28 @@ type Plugin interface {
 	// Name returns the unique name of this plugin type
 	Name() string
 
-	// Create returns a new configured instance of this plugin
-	Create(config map[string]interface{}) (Plugin, error)
+	// Schema describes the config options of this plugin type
+	Schema() *Schema
+
+	// Create returns a new configured instance of this plugin, usually
+	// decoding the config with Schema().Decode
+	Create(config map[string]interface{}, env Env) (Plugin, error)
 
 	// FetchEvents retrieves events from the plugin source
 	FetchEvents(ctx context.Context) ([]models.Event, error)
 }
+
+// Env holds what a plugin instance gets from the host besides its config
+type Env struct {
+	ID     string       // ID of the plugin instance
+	Logger *slog.Logger // Logger with plugin_id and plugin_type attributes
+	Tokens tokens.Store // Persists OAuth tokens across restarts
+}
+
+// Renderer is implemented by plugins that render event summaries and
+// descriptions from Event.Fields, so events can be rendered again after
+// their fields change
+type Renderer interface {
+	// Render sets the event's summary and description from its fields
+	Render(event *models.Event)
+}
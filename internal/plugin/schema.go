@@ -0,0 +1,275 @@
+package plugin
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Type is the type of a config option's value
+type Type string
+
+const (
+	TypeString     Type = "string"
+	TypeInt        Type = "integer"
+	TypeBool       Type = "boolean"
+	TypeStringList Type = "list of strings"
+)
+
+// Option describes a config option of a plugin type
+type Option struct {
+	Name        string `json:"name"`
+	Type        Type   `json:"type"`
+	Default     any    `json:"default,omitempty"` // nil if the option has no default
+	Required    bool   `json:"required,omitempty"`
+	Secret      bool   `json:"secret,omitempty"` // Credentials, which shouldn't be shown
+	Description string `json:"description,omitempty"`
+
+	field []int // Index of the config struct field
+}
+
+// Schema describes the config options of a plugin type. It is built from
+// the struct a plugin decodes its config into, whose fields are tagged with
+// the option name and flags, a default and a description:
+//
+//	type Config struct {
+//		ClientID string `option:"clientId,required" description:"API client ID"`
+//		Token    string `option:"token,secret" description:"Access token"`
+//		DaysBack int    `option:"daysBack" default:"7" description:"Days to look back"`
+//	}
+//
+// Fields may be strings, ints, bools and string slices; embedded structs
+// such as TemplateConfig add their options.
+type Schema struct {
+	Options []Option
+	typ     reflect.Type
+}
+
+// NewSchema builds the schema of a config struct
+func NewSchema(config any) (*Schema, error) {
+	typ := reflect.TypeOf(config)
+	if typ == nil || typ.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("config must be a struct, not %T", config)
+	}
+	s := &Schema{typ: typ}
+	if err := s.addFields(typ, nil); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// MustSchema is like NewSchema but panics if the struct is invalid
+func MustSchema(config any) *Schema {
+	s, err := NewSchema(config)
+	if err != nil {
+		panic(fmt.Sprintf("plugin: %v", err))
+	}
+	return s
+}
+
+func (s *Schema) addFields(typ reflect.Type, index []int) error {
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		fieldIndex := append(append([]int(nil), index...), i)
+		tag, tagged := field.Tag.Lookup("option")
+		if !tagged {
+			if field.Anonymous && field.Type.Kind() == reflect.Struct {
+				if err := s.addFields(field.Type, fieldIndex); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+
+		name, flags, _ := strings.Cut(tag, ",")
+		opt := Option{Name: name, Description: field.Tag.Get("description"), field: fieldIndex}
+		if name == "" {
+			return fmt.Errorf("field %s has no option name", field.Name)
+		}
+		if _, exists := s.Option(name); exists {
+			return fmt.Errorf("option %s is declared twice", name)
+		}
+		for flag := range strings.SplitSeq(flags, ",") {
+			switch flag {
+			case "":
+			case "required":
+				opt.Required = true
+			case "secret":
+				opt.Secret = true
+			default:
+				return fmt.Errorf("option %s has unknown flag %q", name, flag)
+			}
+		}
+
+		switch {
+		case field.Type.Kind() == reflect.String:
+			opt.Type = TypeString
+		case field.Type.Kind() == reflect.Int:
+			opt.Type = TypeInt
+		case field.Type.Kind() == reflect.Bool:
+			opt.Type = TypeBool
+		case field.Type == reflect.TypeOf([]string(nil)):
+			opt.Type = TypeStringList
+		default:
+			return fmt.Errorf("option %s has unsupported type %s", name, field.Type)
+		}
+
+		if text, ok := field.Tag.Lookup("default"); ok {
+			value, err := opt.parseDefault(text)
+			if err != nil {
+				return fmt.Errorf("option %s has invalid default: %w", name, err)
+			}
+			opt.Default = value
+		}
+
+		s.Options = append(s.Options, opt)
+	}
+	return nil
+}
+
+func (o Option) parseDefault(text string) (any, error) {
+	switch o.Type {
+	case TypeInt:
+		return strconv.Atoi(text)
+	case TypeBool:
+		return strconv.ParseBool(text)
+	case TypeStringList:
+		if text == "" {
+			return []string{}, nil
+		}
+		return strings.Split(text, ","), nil
+	default:
+		return text, nil
+	}
+}
+
+// Option returns the option with the given name
+func (s *Schema) Option(name string) (Option, bool) {
+	for _, opt := range s.Options {
+		if opt.Name == name {
+			return opt, true
+		}
+	}
+	return Option{}, false
+}
+
+// Decode checks a plugin instance config against the schema and stores it
+// in dst, a pointer to the schema's struct. Options that are not set get
+// their defaults. All problems found are returned.
+func (s *Schema) Decode(config map[string]interface{}, dst any) error {
+	v := reflect.ValueOf(dst)
+	if v.Kind() != reflect.Pointer || v.Elem().Type() != s.typ {
+		return fmt.Errorf("cannot decode config of type %s into %T", s.typ, dst)
+	}
+	v = v.Elem()
+
+	var errs []error
+	keys := make([]string, 0, len(config))
+	for key := range config {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if _, ok := s.Option(key); !ok {
+			errs = append(errs, fmt.Errorf("unknown option %q", key))
+		}
+	}
+
+	for _, opt := range s.Options {
+		field := v.FieldByIndex(opt.field)
+		value := config[opt.Name]
+		if value == nil {
+			if opt.Required {
+				errs = append(errs, fmt.Errorf("option %q is required", opt.Name))
+			} else if opt.Default != nil {
+				field.Set(reflect.ValueOf(copyValue(opt.Default)))
+			}
+			continue
+		}
+
+		decoded, err := opt.decode(value)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		field.Set(reflect.ValueOf(decoded))
+	}
+	return errors.Join(errs...)
+}
+
+// Check reports whether value is valid for the option
+func (o Option) Check(value any) error {
+	_, err := o.decode(value)
+	return err
+}
+
+// decode converts a value decoded from YAML to the option's type
+func (o Option) decode(value any) (any, error) {
+	var decoded any
+	switch o.Type {
+	case TypeString:
+		if s, ok := value.(string); ok {
+			decoded = s
+		}
+	case TypeInt:
+		if n, ok := value.(int); ok {
+			decoded = n
+		}
+	case TypeBool:
+		if b, ok := value.(bool); ok {
+			decoded = b
+		}
+	case TypeStringList:
+		if items, ok := value.([]interface{}); ok {
+			list := make([]string, 0, len(items))
+			for _, item := range items {
+				s, ok := item.(string)
+				if !ok {
+					return nil, fmt.Errorf("option %q must be of type %s, not a list with %s items", o.Name, o.Type, typeOf(item))
+				}
+				list = append(list, s)
+			}
+			decoded = list
+		}
+	}
+
+	if decoded == nil {
+		return nil, fmt.Errorf("option %q must be of type %s, not %s", o.Name, o.Type, typeOf(value))
+	}
+	if o.Required && decoded == "" {
+		return nil, fmt.Errorf("option %q must not be empty", o.Name)
+	}
+	return decoded, nil
+}
+
+// typeOf names the type of a value decoded from YAML
+func typeOf(value any) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case string:
+		return "string"
+	case int, int64, uint64:
+		return "integer"
+	case float64:
+		return "number"
+	case bool:
+		return "boolean"
+	case []interface{}:
+		return "list"
+	case map[string]interface{}:
+		return "mapping"
+	default:
+		return fmt.Sprintf("%T", value)
+	}
+}
+
+func copyValue(value any) any {
+	if list, ok := value.([]string); ok {
+		return append([]string(nil), list...)
+	}
+	return value
+}
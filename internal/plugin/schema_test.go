@@ -0,0 +1,186 @@
+package plugin
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type testConfig struct {
+	ClientID   string   `option:"clientId,required" description:"Client ID"`
+	Token      string   `option:"token,secret" description:"Access token"`
+	DaysBack   int      `option:"daysBack" default:"7" description:"Days to look back"`
+	Recurring  bool     `option:"recurring" default:"true" description:"Publish series"`
+	Categories []string `option:"categories" default:"tv,shows" description:"Categories"`
+	TemplateConfig
+}
+
+var testSchema = MustSchema(testConfig{})
+
+func TestNewSchema(t *testing.T) {
+	want := []Option{
+		{Name: "clientId", Type: TypeString, Required: true, Description: "Client ID"},
+		{Name: "token", Type: TypeString, Secret: true, Description: "Access token"},
+		{Name: "daysBack", Type: TypeInt, Default: 7, Description: "Days to look back"},
+		{Name: "recurring", Type: TypeBool, Default: true, Description: "Publish series"},
+		{Name: "categories", Type: TypeStringList, Default: []string{"tv", "shows"}, Description: "Categories"},
+		{Name: "summaryTemplate", Type: TypeString, Description: "Go text/template for event summaries"},
+		{Name: "descriptionTemplate", Type: TypeString, Description: "Go text/template for event descriptions"},
+	}
+	if len(testSchema.Options) != len(want) {
+		t.Fatalf("got %d options, want %d", len(testSchema.Options), len(want))
+	}
+	for i, opt := range testSchema.Options {
+		opt.field = nil
+		if !reflect.DeepEqual(opt, want[i]) {
+			t.Errorf("option %d = %+v, want %+v", i, opt, want[i])
+		}
+	}
+}
+
+func TestNewSchemaErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		config any
+		want   string
+	}{
+		{"not a struct", "config", "config must be a struct"},
+		{"no name", struct {
+			A string `option:",required"`
+		}{}, "has no option name"},
+		{"unknown flag", struct {
+			A string `option:"a,hidden"`
+		}{}, `unknown flag "hidden"`},
+		{"unsupported type", struct {
+			A float64 `option:"a"`
+		}{}, "unsupported type float64"},
+		{"invalid default", struct {
+			A int `option:"a" default:"many"`
+		}{}, "invalid default"},
+		{"declared twice", struct {
+			A string `option:"a"`
+			B string `option:"a"`
+		}{}, "declared twice"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewSchema(tt.config)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestSchemaDecode(t *testing.T) {
+	tests := []struct {
+		name   string
+		config map[string]interface{}
+		want   testConfig
+		errs   []string
+	}{
+		{
+			name:   "defaults",
+			config: map[string]interface{}{"clientId": "id"},
+			want:   testConfig{ClientID: "id", DaysBack: 7, Recurring: true, Categories: []string{"tv", "shows"}},
+		},
+		{
+			name: "all options",
+			config: map[string]interface{}{
+				"clientId":        "id",
+				"token":           "secret",
+				"daysBack":        30,
+				"recurring":       false,
+				"categories":      []interface{}{"anime"},
+				"summaryTemplate": "{{.show}}",
+			},
+			want: testConfig{
+				ClientID: "id", Token: "secret", DaysBack: 30, Categories: []string{"anime"},
+				TemplateConfig: TemplateConfig{SummaryTemplate: "{{.show}}"},
+			},
+		},
+		{
+			name:   "empty list",
+			config: map[string]interface{}{"clientId": "id", "categories": []interface{}{}},
+			want:   testConfig{ClientID: "id", DaysBack: 7, Recurring: true, Categories: []string{}},
+		},
+		{
+			name:   "required",
+			config: map[string]interface{}{},
+			errs:   []string{`option "clientId" is required`},
+		},
+		{
+			name:   "required but empty",
+			config: map[string]interface{}{"clientId": ""},
+			errs:   []string{`option "clientId" must not be empty`},
+		},
+		{
+			name: "type errors",
+			config: map[string]interface{}{
+				"clientId":   42,
+				"daysBack":   "7",
+				"recurring":  "yes",
+				"categories": []interface{}{"tv", 1},
+			},
+			errs: []string{
+				`option "clientId" must be of type string, not integer`,
+				`option "daysBack" must be of type integer, not string`,
+				`option "recurring" must be of type boolean, not string`,
+				`option "categories" must be of type list of strings, not a list with integer items`,
+			},
+		},
+		{
+			name:   "unknown options",
+			config: map[string]interface{}{"clientId": "id", "tokn": "x", "daysback": 1.5},
+			errs:   []string{`unknown option "daysback"`, `unknown option "tokn"`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got testConfig
+			err := testSchema.Decode(tt.config, &got)
+			if len(tt.errs) > 0 {
+				if err == nil {
+					t.Fatalf("Decode succeeded, want %q", tt.errs)
+				}
+				if msgs := strings.Split(err.Error(), "\n"); !reflect.DeepEqual(msgs, tt.errs) {
+					t.Errorf("errors = %q, want %q", msgs, tt.errs)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Decode: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSchemaDecodeCopiesDefaults(t *testing.T) {
+	var a, b testConfig
+	if err := testSchema.Decode(map[string]interface{}{"clientId": "a"}, &a); err != nil {
+		t.Fatal(err)
+	}
+	a.Categories[0] = "changed"
+	if err := testSchema.Decode(map[string]interface{}{"clientId": "b"}, &b); err != nil {
+		t.Fatal(err)
+	}
+	if b.Categories[0] != "tv" {
+		t.Errorf("default changed through a decoded config: %q", b.Categories)
+	}
+}
+
+func TestSchemaDecodeWrongType(t *testing.T) {
+	var other struct{ ClientID string }
+	if err := testSchema.Decode(map[string]interface{}{"clientId": "id"}, &other); err == nil {
+		t.Error("Decode into another type succeeded")
+	}
+	var cfg testConfig
+	if err := testSchema.Decode(map[string]interface{}{"clientId": "id"}, cfg); err == nil {
+		t.Error("Decode into a non-pointer succeeded")
+	}
+}
@@ -0,0 +1,100 @@
+package plugin
+
+import (
+	"bytes"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"text/template"
+
+	"github.com/jacobsee/modcal/internal/models"
+)
+
+// TemplateConfig holds the optional template options of a plugin instance
+// config. Plugins using Templates embed it in their config struct.
+type TemplateConfig struct {
+	SummaryTemplate     string `option:"summaryTemplate" description:"Go text/template for event summaries"`
+	DescriptionTemplate string `option:"descriptionTemplate" description:"Go text/template for event descriptions"`
+}
+
+// Templates renders event summaries and descriptions from the raw fields a
+// plugin stores in Event.Fields. Fields are referenced by name, e.g.
+// "{{.show}} - Episode {{.episode}}".
+type Templates struct {
+	summary     *eventTemplate
+	description *eventTemplate
+}
+
+// eventTemplate is one template with the plugin's default to fall back to if
+// the configured one fails to render
+type eventTemplate struct {
+	configured *template.Template
+	fallback   *template.Template
+	logger     *slog.Logger
+	logOnce    sync.Once
+}
+
+// NewTemplates parses the templates of a plugin instance config. Templates
+// that are not configured use the given defaults. Rendering failures are
+// logged to logger.
+func NewTemplates(config TemplateConfig, logger *slog.Logger, defaultSummary, defaultDescription string) (*Templates, error) {
+	summary, err := newEventTemplate("summaryTemplate", config.SummaryTemplate, logger, defaultSummary)
+	if err != nil {
+		return nil, err
+	}
+	description, err := newEventTemplate("descriptionTemplate", config.DescriptionTemplate, logger, defaultDescription)
+	if err != nil {
+		return nil, err
+	}
+	return &Templates{summary: summary, description: description}, nil
+}
+
+func newEventTemplate(name, text string, logger *slog.Logger, defaultText string) (*eventTemplate, error) {
+	fallback, err := parseTemplate(name, defaultText)
+	if err != nil {
+		return nil, fmt.Errorf("invalid default %s: %w", name, err)
+	}
+
+	t := &eventTemplate{configured: fallback, fallback: fallback, logger: logger}
+	if text != "" {
+		if t.configured, err = parseTemplate(name, text); err != nil {
+			return nil, fmt.Errorf("invalid %s: %w", name, err)
+		}
+	}
+	return t, nil
+}
+
+func parseTemplate(name, text string) (*template.Template, error) {
+	// Referencing a field the plugin does not provide is an error rather
+	// than "<no value>" in the calendar
+	return template.New(name).Option("missingkey=error").Parse(text)
+}
+
+// Render sets the event's summary and description from its fields
+func (t *Templates) Render(event *models.Event) {
+	event.Summary = t.summary.render(event.Fields)
+	event.Description = t.description.render(event.Fields)
+}
+
+func (t *eventTemplate) render(fields map[string]interface{}) string {
+	text, err := execute(t.configured, fields)
+	if err != nil && t.configured != t.fallback {
+		t.logOnce.Do(func() {
+			t.logger.Warn("Template failed, using default", "template", t.configured.Name(), "error", err)
+		})
+		text, err = execute(t.fallback, fields)
+	}
+	if err != nil {
+		t.logger.Error("Default template failed", "template", t.fallback.Name(), "error", err)
+	}
+	return text
+}
+
+func execute(t *template.Template, fields map[string]interface{}) (string, error) {
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, fields); err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(buf.String()), nil
+}
@@ -0,0 +1,169 @@
+package recurrence
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/jacobsee/modcal/internal/models"
+)
+
+// Expand replaces every recurring series in events with its individual
+// occurrences that overlap [from, to). Overrides (events with a RecurrenceID)
+// replace the occurrence they refer to. Non-recurring events are returned
+// unchanged. Each occurrence gets a UID derived from the series UID and its
+// original start, see InstanceUID.
+func Expand(events []models.Event, from, to time.Time) []models.Event {
+	masters := make(map[string]bool)
+	for _, event := range events {
+		if event.IsRecurring() && event.RecurrenceID.IsZero() {
+			masters[event.UID] = true
+		}
+	}
+
+	overrides := make(map[string]map[int64]models.Event)
+	for _, event := range events {
+		if event.RecurrenceID.IsZero() || !masters[event.UID] {
+			continue
+		}
+		if overrides[event.UID] == nil {
+			overrides[event.UID] = make(map[int64]models.Event)
+		}
+		overrides[event.UID][event.RecurrenceID.Unix()] = event
+	}
+
+	var expanded []models.Event
+	for _, event := range events {
+		switch {
+		case !event.RecurrenceID.IsZero():
+			// Overrides are emitted with their master; orphans stand alone
+			if !masters[event.UID] {
+				orphan := event
+				orphan.UID = InstanceUID(event.UID, event.RecurrenceID)
+				orphan.RecurrenceID = time.Time{}
+				expanded = append(expanded, orphan)
+			}
+		case event.IsRecurring():
+			expanded = append(expanded, expandSeries(event, overrides[event.UID], from, to)...)
+		default:
+			expanded = append(expanded, event)
+		}
+	}
+
+	return expanded
+}
+
+// Starts returns the original start times of the series' occurrences that
+// begin within [from, to), including RDATEs and excluding EXDATEs
+func Starts(event models.Event, from, to time.Time) ([]time.Time, error) {
+	// Occurrences keep their wall clock time in the event's zone across
+	// DST changes
+	dtstart := event.StartTime
+	if loc := event.Zone(); loc != nil {
+		dtstart = dtstart.In(loc)
+	}
+
+	var starts []time.Time
+	if event.Recurrence.Rule != "" {
+		rule, err := ParseRule(event.Recurrence.Rule, dtstart.Location())
+		if err != nil {
+			return nil, err
+		}
+		starts = rule.Occurrences(dtstart, from, to)
+	} else if !event.StartTime.Before(from) && event.StartTime.Before(to) {
+		starts = []time.Time{event.StartTime}
+	}
+
+	for _, rdate := range event.Recurrence.RDates {
+		if !rdate.Before(from) && rdate.Before(to) {
+			starts = append(starts, rdate)
+		}
+	}
+	sort.Slice(starts, func(i, j int) bool { return starts[i].Before(starts[j]) })
+
+	var result []time.Time
+	seen := make(map[int64]bool, len(starts))
+	for _, start := range starts {
+		if seen[start.Unix()] || containsTime(event.Recurrence.ExDates, start) {
+			continue
+		}
+		seen[start.Unix()] = true
+		result = append(result, start)
+	}
+
+	return result, nil
+}
+
+// InstanceUID derives a unique UID for one occurrence of a recurring event
+func InstanceUID(uid string, start time.Time) string {
+	return fmt.Sprintf("%s-%s", uid, start.UTC().Format("20060102T150405Z"))
+}
+
+func expandSeries(master models.Event, overrides map[int64]models.Event, from, to time.Time) []models.Event {
+	var duration time.Duration
+	if !master.EndTime.IsZero() {
+		duration = master.EndTime.Sub(master.StartTime)
+	}
+
+	// Occurrences that start before the range may still overlap it
+	starts, err := Starts(master, from.Add(-duration), to)
+	if err != nil {
+		// An unusable rule still leaves the first occurrence
+		starts = []time.Time{master.StartTime}
+	}
+
+	var events []models.Event
+	for _, start := range starts {
+		instance := master
+		if override, ok := overrides[start.Unix()]; ok {
+			instance = override
+		} else {
+			instance.StartTime = start
+			if !master.EndTime.IsZero() {
+				instance.EndTime = start.Add(duration)
+			}
+			instance.Categories = append([]string(nil), master.Categories...)
+		}
+		instance.UID = InstanceUID(master.UID, start)
+		instance.Recurrence = nil
+		instance.RecurrenceID = time.Time{}
+
+		if overlaps(instance, from, to) {
+			events = append(events, instance)
+		}
+	}
+
+	// Overrides may move an occurrence into the range from outside of it
+	for recurrenceID, override := range overrides {
+		start := time.Unix(recurrenceID, 0)
+		if containsTime(starts, start) || containsTime(master.Recurrence.ExDates, start) {
+			continue
+		}
+		if !overlaps(override, from, to) {
+			continue
+		}
+		instance := override
+		instance.UID = InstanceUID(master.UID, override.RecurrenceID)
+		instance.RecurrenceID = time.Time{}
+		events = append(events, instance)
+	}
+
+	return events
+}
+
+func overlaps(event models.Event, from, to time.Time) bool {
+	end := event.EndTime
+	if end.IsZero() {
+		end = event.StartTime
+	}
+	return event.StartTime.Before(to) && !end.Before(from)
+}
+
+func containsTime(times []time.Time, t time.Time) bool {
+	for _, candidate := range times {
+		if candidate.Equal(t) {
+			return true
+		}
+	}
+	return false
+}
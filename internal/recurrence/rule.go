@@ -0,0 +1,425 @@
+package recurrence
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// maxOccurrences caps the number of instances generated for one series
+	maxOccurrences = 5000
+	// maxPeriods caps the number of periods scanned for one series, so rules
+	// that never match (e.g. BYMONTHDAY=31;BYMONTH=2) cannot loop forever
+	maxPeriods = 50000
+)
+
+// Rule is a parsed RFC 5545 recurrence rule
+type Rule struct {
+	freq       string
+	interval   int
+	count      int
+	until      time.Time
+	byDay      []weekdayNum
+	byMonthDay []int
+	byMonth    []time.Month
+	byHour     []int
+	byMinute   []int
+	bySecond   []int
+	bySetPos   []int
+	weekStart  time.Weekday
+}
+
+// weekdayNum is a BYDAY entry such as "MO" or "-1FR"
+type weekdayNum struct {
+	ordinal int
+	weekday time.Weekday
+}
+
+var weekdayCodes = map[string]time.Weekday{
+	"SU": time.Sunday,
+	"MO": time.Monday,
+	"TU": time.Tuesday,
+	"WE": time.Wednesday,
+	"TH": time.Thursday,
+	"FR": time.Friday,
+	"SA": time.Saturday,
+}
+
+// ParseRule parses an RRULE value. Floating and date-only UNTIL values are
+// interpreted in loc, which should be the location of the series' DTSTART.
+func ParseRule(value string, loc *time.Location) (*Rule, error) {
+	rule := &Rule{interval: 1, weekStart: time.Monday}
+
+	for _, part := range strings.Split(value, ";") {
+		key, val, ok := strings.Cut(part, "=")
+		if !ok {
+			return nil, fmt.Errorf("malformed rule part %q", part)
+		}
+
+		var err error
+		switch strings.ToUpper(key) {
+		case "FREQ":
+			rule.freq = strings.ToUpper(val)
+		case "INTERVAL":
+			rule.interval, err = strconv.Atoi(val)
+			if err == nil && rule.interval < 1 {
+				err = fmt.Errorf("interval must be positive")
+			}
+		case "COUNT":
+			rule.count, err = strconv.Atoi(val)
+		case "UNTIL":
+			rule.until, err = parseUntil(val, loc)
+		case "BYDAY":
+			rule.byDay, err = parseByDay(val)
+		case "BYMONTHDAY":
+			rule.byMonthDay, err = parseIntList(val)
+		case "BYMONTH":
+			var months []int
+			months, err = parseIntList(val)
+			for _, m := range months {
+				rule.byMonth = append(rule.byMonth, time.Month(m))
+			}
+		case "BYHOUR":
+			rule.byHour, err = parseRange(val, 0, 23)
+		case "BYMINUTE":
+			rule.byMinute, err = parseRange(val, 0, 59)
+		case "BYSECOND":
+			rule.bySecond, err = parseRange(val, 0, 59)
+		case "BYSETPOS":
+			rule.bySetPos, err = parseIntList(val)
+		case "WKST":
+			var ok bool
+			if rule.weekStart, ok = weekdayCodes[strings.ToUpper(val)]; !ok {
+				err = fmt.Errorf("malformed weekday %q", val)
+			}
+		default:
+			// Dropping parts such as BYWEEKNO or BYYEARDAY would expand to
+			// wrong occurrences, so such rules are rejected
+			return nil, fmt.Errorf("unsupported rule part %s", key)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s: %w", key, err)
+		}
+	}
+
+	switch rule.freq {
+	case "DAILY", "WEEKLY", "MONTHLY", "YEARLY":
+	default:
+		return nil, fmt.Errorf("unsupported FREQ %q", rule.freq)
+	}
+
+	return rule, nil
+}
+
+func parseUntil(value string, loc *time.Location) (time.Time, error) {
+	switch {
+	case strings.HasSuffix(value, "Z"):
+		return time.Parse("20060102T150405Z", value)
+	case len(value) == len("20060102"):
+		// A date-only UNTIL includes the whole day
+		t, err := time.ParseInLocation("20060102", value, loc)
+		return t.AddDate(0, 0, 1).Add(-time.Nanosecond), err
+	default:
+		return time.ParseInLocation("20060102T150405", value, loc)
+	}
+}
+
+func parseByDay(value string) ([]weekdayNum, error) {
+	var days []weekdayNum
+	for _, item := range strings.Split(value, ",") {
+		item = strings.ToUpper(strings.TrimSpace(item))
+		if len(item) < 2 {
+			return nil, fmt.Errorf("malformed weekday %q", item)
+		}
+		weekday, ok := weekdayCodes[item[len(item)-2:]]
+		if !ok {
+			return nil, fmt.Errorf("malformed weekday %q", item)
+		}
+		day := weekdayNum{weekday: weekday}
+		if prefix := item[:len(item)-2]; prefix != "" {
+			ordinal, err := strconv.Atoi(prefix)
+			if err != nil {
+				return nil, fmt.Errorf("malformed weekday %q", item)
+			}
+			day.ordinal = ordinal
+		}
+		days = append(days, day)
+	}
+	return days, nil
+}
+
+func parseIntList(value string) ([]int, error) {
+	var values []int
+	for _, item := range strings.Split(value, ",") {
+		n, err := strconv.Atoi(strings.TrimSpace(item))
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, n)
+	}
+	return values, nil
+}
+
+// parseRange parses a list of integers between min and max
+func parseRange(value string, min, max int) ([]int, error) {
+	values, err := parseIntList(value)
+	if err != nil {
+		return nil, err
+	}
+	for _, n := range values {
+		if n < min || n > max {
+			return nil, fmt.Errorf("%d is not between %d and %d", n, min, max)
+		}
+	}
+	return values, nil
+}
+
+// Occurrences returns the start times generated by the rule for a series
+// starting at dtstart that fall within [from, limit). COUNT is honoured from
+// the start of the series regardless of the range. As in RFC 5545, dtstart
+// is always the first occurrence, even if the rule doesn't match it.
+func (r *Rule) Occurrences(dtstart, from, limit time.Time) []time.Time {
+	var result []time.Time
+	generated := 0
+	if !containsTime(r.candidates(dtstart, 0), dtstart) {
+		generated++
+		if !dtstart.Before(from) && dtstart.Before(limit) {
+			result = append(result, dtstart)
+		}
+	}
+
+	for period := 0; period < maxPeriods; period++ {
+		candidates := r.candidates(dtstart, period*r.interval)
+		if len(candidates) == 0 && r.periodStart(dtstart, period*r.interval).After(limit) {
+			break
+		}
+
+		for _, t := range candidates {
+			if t.Before(dtstart) {
+				continue
+			}
+			if !r.until.IsZero() && t.After(r.until) {
+				return result
+			}
+			if r.count > 0 && generated >= r.count {
+				return result
+			}
+			if !t.Before(limit) || len(result) >= maxOccurrences {
+				return result
+			}
+			generated++
+			if !t.Before(from) {
+				result = append(result, t)
+			}
+		}
+	}
+
+	return result
+}
+
+// periodStart returns the first day of the n-th period after dtstart
+func (r *Rule) periodStart(dtstart time.Time, n int) time.Time {
+	y, m, d := dtstart.Date()
+	loc := dtstart.Location()
+	switch r.freq {
+	case "DAILY":
+		return time.Date(y, m, d+n, 0, 0, 0, 0, loc)
+	case "WEEKLY":
+		offset := (int(dtstart.Weekday()) - int(r.weekStart) + 7) % 7
+		return time.Date(y, m, d-offset+7*n, 0, 0, 0, 0, loc)
+	case "MONTHLY":
+		return time.Date(y, m+time.Month(n), 1, 0, 0, 0, 0, loc)
+	default:
+		return time.Date(y+n, time.January, 1, 0, 0, 0, 0, loc)
+	}
+}
+
+// candidates returns the sorted instances within the n-th period
+func (r *Rule) candidates(dtstart time.Time, n int) []time.Time {
+	start := r.periodStart(dtstart, n)
+	var days []time.Time
+
+	switch r.freq {
+	case "DAILY":
+		days = []time.Time{start}
+	case "WEEKLY":
+		weekdays := r.byDay
+		if len(weekdays) == 0 {
+			weekdays = []weekdayNum{{weekday: dtstart.Weekday()}}
+		}
+		for i := 0; i < 7; i++ {
+			day := start.AddDate(0, 0, i)
+			for _, wd := range weekdays {
+				if day.Weekday() == wd.weekday {
+					days = append(days, day)
+				}
+			}
+		}
+	case "MONTHLY":
+		days = r.monthDays(dtstart, start.Year(), start.Month())
+	case "YEARLY":
+		months := r.byMonth
+		if len(months) == 0 && (len(r.byMonthDay) > 0 || len(r.byDay) == 0) {
+			months = []time.Month{dtstart.Month()}
+		}
+		if len(months) == 0 {
+			// BYDAY without BYMONTH applies to the whole year
+			days = weekdaysInRange(r.byDay, start, start.AddDate(1, 0, 0))
+		}
+		for _, month := range months {
+			days = append(days, r.monthDays(dtstart, start.Year(), month)...)
+		}
+	}
+
+	// BYHOUR, BYMINUTE and BYSECOND expand each day into several times,
+	// defaulting to the time of day of dtstart
+	hours := orDefault(r.byHour, dtstart.Hour())
+	minutes := orDefault(r.byMinute, dtstart.Minute())
+	seconds := orDefault(r.bySecond, dtstart.Second())
+
+	var times []time.Time
+	for _, day := range days {
+		if !r.matches(day) {
+			continue
+		}
+		for _, hour := range hours {
+			for _, minute := range minutes {
+				for _, second := range seconds {
+					times = append(times, time.Date(day.Year(), day.Month(), day.Day(),
+						hour, minute, second, 0, dtstart.Location()))
+				}
+			}
+		}
+	}
+	sort.Slice(times, func(i, j int) bool { return times[i].Before(times[j]) })
+
+	return r.applySetPos(times)
+}
+
+// monthDays expands BYMONTHDAY/BYDAY within a single month
+func (r *Rule) monthDays(dtstart time.Time, year int, month time.Month) []time.Time {
+	loc := dtstart.Location()
+	first := time.Date(year, month, 1, 0, 0, 0, 0, loc)
+	next := first.AddDate(0, 1, 0)
+	daysInMonth := next.AddDate(0, 0, -1).Day()
+
+	if len(r.byMonthDay) == 0 && len(r.byDay) == 0 {
+		if dtstart.Day() > daysInMonth {
+			return nil // e.g. the 31st in a 30 day month is skipped
+		}
+		return []time.Time{time.Date(year, month, dtstart.Day(), 0, 0, 0, 0, loc)}
+	}
+
+	var days []time.Time
+	if len(r.byMonthDay) > 0 {
+		for _, md := range r.byMonthDay {
+			day := md
+			if md < 0 {
+				day = daysInMonth + md + 1
+			}
+			if day >= 1 && day <= daysInMonth {
+				days = append(days, time.Date(year, month, day, 0, 0, 0, 0, loc))
+			}
+		}
+		return days // BYDAY, if present, filters these in matches
+	}
+
+	return weekdaysInRange(r.byDay, first, next)
+}
+
+// weekdaysInRange returns days in [from, to) matching BYDAY entries, where
+// ordinals count occurrences within the range
+func weekdaysInRange(byDay []weekdayNum, from, to time.Time) []time.Time {
+	var days []time.Time
+	for _, wd := range byDay {
+		var matching []time.Time
+		for day := from; day.Before(to); day = day.AddDate(0, 0, 1) {
+			if day.Weekday() == wd.weekday {
+				matching = append(matching, day)
+			}
+		}
+		switch {
+		case wd.ordinal == 0:
+			days = append(days, matching...)
+		case wd.ordinal > 0 && wd.ordinal <= len(matching):
+			days = append(days, matching[wd.ordinal-1])
+		case wd.ordinal < 0 && -wd.ordinal <= len(matching):
+			days = append(days, matching[len(matching)+wd.ordinal])
+		}
+	}
+	return days
+}
+
+// matches applies the limiting BYxxx parts to an expanded day
+func (r *Rule) matches(day time.Time) bool {
+	if len(r.byMonth) > 0 && !containsMonth(r.byMonth, day.Month()) {
+		return false
+	}
+
+	// For DAILY rules, and when BYMONTHDAY already expanded the days,
+	// BYDAY only limits by weekday
+	if len(r.byDay) > 0 && (r.freq == "DAILY" || len(r.byMonthDay) > 0) {
+		found := false
+		for _, wd := range r.byDay {
+			if wd.weekday == day.Weekday() {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	if r.freq == "DAILY" && len(r.byMonthDay) > 0 {
+		daysInMonth := time.Date(day.Year(), day.Month()+1, 0, 0, 0, 0, 0, day.Location()).Day()
+		found := false
+		for _, md := range r.byMonthDay {
+			if md == day.Day() || (md < 0 && daysInMonth+md+1 == day.Day()) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (r *Rule) applySetPos(times []time.Time) []time.Time {
+	if len(r.bySetPos) == 0 || len(times) == 0 {
+		return times
+	}
+	var selected []time.Time
+	for _, pos := range r.bySetPos {
+		switch {
+		case pos > 0 && pos <= len(times):
+			selected = append(selected, times[pos-1])
+		case pos < 0 && -pos <= len(times):
+			selected = append(selected, times[len(times)+pos])
+		}
+	}
+	sort.Slice(selected, func(i, j int) bool { return selected[i].Before(selected[j]) })
+	return selected
+}
+
+func orDefault(values []int, def int) []int {
+	if len(values) == 0 {
+		return []int{def}
+	}
+	return values
+}
+
+func containsMonth(months []time.Month, month time.Month) bool {
+	for _, m := range months {
+		if m == month {
+			return true
+		}
+	}
+	return false
+}
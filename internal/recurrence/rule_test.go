@@ -0,0 +1,120 @@
+package recurrence
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestOccurrences(t *testing.T) {
+	tests := []struct {
+		name    string
+		rule    string
+		dtstart time.Time
+		want    []string // Formatted as "2006-01-02 15:04"
+	}{
+		{
+			name:    "daily with BYHOUR",
+			rule:    "FREQ=DAILY;COUNT=4;BYHOUR=9,17",
+			dtstart: time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC),
+			want:    []string{"2024-03-01 09:00", "2024-03-01 17:00", "2024-03-02 09:00", "2024-03-02 17:00"},
+		},
+		{
+			name:    "daily with BYHOUR and BYMINUTE",
+			rule:    "FREQ=DAILY;COUNT=4;BYHOUR=8;BYMINUTE=0,30",
+			dtstart: time.Date(2024, 3, 1, 8, 0, 0, 0, time.UTC),
+			want:    []string{"2024-03-01 08:00", "2024-03-01 08:30", "2024-03-02 08:00", "2024-03-02 08:30"},
+		},
+		{
+			name:    "weekly with BYDAY",
+			rule:    "FREQ=WEEKLY;COUNT=4;BYDAY=MO,WE",
+			dtstart: time.Date(2024, 3, 4, 20, 0, 0, 0, time.UTC),
+			want:    []string{"2024-03-04 20:00", "2024-03-06 20:00", "2024-03-11 20:00", "2024-03-13 20:00"},
+		},
+		{
+			// RFC 5545 section 3.3.10
+			name:    "biweekly with WKST=MO",
+			rule:    "FREQ=WEEKLY;INTERVAL=2;COUNT=4;BYDAY=TU,SU;WKST=MO",
+			dtstart: time.Date(1997, 8, 5, 9, 0, 0, 0, time.UTC),
+			want:    []string{"1997-08-05 09:00", "1997-08-10 09:00", "1997-08-19 09:00", "1997-08-24 09:00"},
+		},
+		{
+			name:    "biweekly with WKST=SU",
+			rule:    "FREQ=WEEKLY;INTERVAL=2;COUNT=4;BYDAY=TU,SU;WKST=SU",
+			dtstart: time.Date(1997, 8, 5, 9, 0, 0, 0, time.UTC),
+			want:    []string{"1997-08-05 09:00", "1997-08-17 09:00", "1997-08-19 09:00", "1997-08-31 09:00"},
+		},
+		{
+			name:    "monthly on the last Friday",
+			rule:    "FREQ=MONTHLY;COUNT=3;BYDAY=-1FR",
+			dtstart: time.Date(2024, 1, 26, 18, 0, 0, 0, time.UTC),
+			want:    []string{"2024-01-26 18:00", "2024-02-23 18:00", "2024-03-29 18:00"},
+		},
+		{
+			name:    "monthly on the 31st skips short months",
+			rule:    "FREQ=MONTHLY;COUNT=3",
+			dtstart: time.Date(2024, 1, 31, 12, 0, 0, 0, time.UTC),
+			want:    []string{"2024-01-31 12:00", "2024-03-31 12:00", "2024-05-31 12:00"},
+		},
+		{
+			name:    "last weekday of the month",
+			rule:    "FREQ=MONTHLY;COUNT=2;BYDAY=MO,TU,WE,TH,FR;BYSETPOS=-1",
+			dtstart: time.Date(2024, 3, 29, 17, 0, 0, 0, time.UTC),
+			want:    []string{"2024-03-29 17:00", "2024-04-30 17:00"},
+		},
+		{
+			// DTSTART is a Sunday the rule doesn't match, but still the
+			// first of the three occurrences
+			name:    "unsynchronized DTSTART",
+			rule:    "FREQ=WEEKLY;COUNT=3;BYDAY=TU",
+			dtstart: time.Date(2024, 3, 3, 20, 0, 0, 0, time.UTC),
+			want:    []string{"2024-03-03 20:00", "2024-03-05 20:00", "2024-03-12 20:00"},
+		},
+		{
+			name:    "yearly until a date",
+			rule:    "FREQ=YEARLY;UNTIL=20260101",
+			dtstart: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+			want:    []string{"2024-01-01 00:00", "2025-01-01 00:00", "2026-01-01 00:00"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule, err := ParseRule(tt.rule, time.UTC)
+			if err != nil {
+				t.Fatalf("ParseRule: %v", err)
+			}
+			var got []string
+			for _, occurrence := range rule.Occurrences(tt.dtstart, tt.dtstart, tt.dtstart.AddDate(5, 0, 0)) {
+				got = append(got, occurrence.Format("2006-01-02 15:04"))
+			}
+			if strings.Join(got, ", ") != strings.Join(tt.want, ", ") {
+				t.Errorf("got  %v\nwant %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseRuleErrors(t *testing.T) {
+	tests := []struct {
+		rule string
+		want string
+	}{
+		{"FREQ=HOURLY", "unsupported FREQ"},
+		{"FREQ=YEARLY;BYWEEKNO=20", "unsupported rule part BYWEEKNO"},
+		{"FREQ=YEARLY;BYYEARDAY=100", "unsupported rule part BYYEARDAY"},
+		{"FREQ=DAILY;BYHOUR=24", "invalid BYHOUR"},
+		{"FREQ=WEEKLY;WKST=XX", "invalid WKST"},
+		{"FREQ=DAILY;INTERVAL=0", "invalid INTERVAL"},
+		{"FREQ=DAILY;COUNT", "malformed rule part"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.rule, func(t *testing.T) {
+			_, err := ParseRule(tt.rule, time.UTC)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
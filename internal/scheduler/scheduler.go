@@ -0,0 +1,168 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"math/rand/v2"
+	"sync"
+	"time"
+)
+
+// Schedule decides when a plugin instance is refreshed next
+type Schedule interface {
+	// Next returns the first run after t, or the zero time if there is none
+	Next(t time.Time) time.Time
+}
+
+// Interval is a schedule that runs at a fixed interval
+type Interval time.Duration
+
+func (i Interval) Next(t time.Time) time.Time {
+	return t.Add(time.Duration(i))
+}
+
+// RefreshFunc refreshes the events of one plugin instance
+type RefreshFunc func(ctx context.Context, pluginID string) error
+
+// Scheduler refreshes plugin instances on their own schedules. Each run is
+// delayed by a random jitter so instances with the same schedule don't call
+// their APIs at the same moment.
+type Scheduler struct {
+	mu      sync.Mutex
+	refresh RefreshFunc
+	jitter  time.Duration
+	entries map[string]*entry
+	logger  *slog.Logger
+}
+
+// entry is the schedule and next run of one plugin instance
+type entry struct {
+	schedule Schedule
+	next     time.Time
+	running  bool
+}
+
+// New creates a scheduler that calls refresh for due plugin instances.
+// Runs are delayed by up to jitter, but at most half the time between runs.
+func New(refresh RefreshFunc, jitter time.Duration, logger *slog.Logger) *Scheduler {
+	return &Scheduler{
+		refresh: refresh,
+		jitter:  jitter,
+		entries: make(map[string]*entry),
+		logger:  logger,
+	}
+}
+
+// Add schedules a plugin instance, starting from now
+func (s *Scheduler) Add(pluginID string, schedule Schedule) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.entries[pluginID]; exists {
+		return fmt.Errorf("plugin %s already scheduled", pluginID)
+	}
+
+	e := &entry{schedule: schedule}
+	e.next = s.nextRun(e, time.Now())
+	if e.next.IsZero() {
+		return fmt.Errorf("schedule of plugin %s never runs", pluginID)
+	}
+	s.entries[pluginID] = e
+	return nil
+}
+
+// NextRun returns when a plugin instance is refreshed next
+func (s *Scheduler) NextRun(pluginID string) (time.Time, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, ok := s.entries[pluginID]
+	if !ok {
+		return time.Time{}, false
+	}
+	return e.next, true
+}
+
+// nextRun returns the next run of e after now, including jitter
+func (s *Scheduler) nextRun(e *entry, now time.Time) time.Time {
+	next := e.schedule.Next(now)
+	if next.IsZero() {
+		return next
+	}
+
+	jitter := s.jitter
+	if gap := next.Sub(now) / 2; jitter > gap {
+		jitter = gap
+	}
+	if jitter > 0 {
+		next = next.Add(rand.N(jitter))
+	}
+	return next
+}
+
+// Run refreshes plugin instances as they become due until ctx is done.
+// Instances whose previous refresh is still running are skipped.
+func (s *Scheduler) Run(ctx context.Context) {
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-timer.C:
+		}
+
+		timer.Reset(s.runDue(ctx, time.Now()))
+	}
+}
+
+// runDue starts the refreshes that are due at now and returns the time
+// until the next one
+func (s *Scheduler) runDue(ctx context.Context, now time.Time) time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var earliest time.Time
+	for id, e := range s.entries {
+		if e.next.IsZero() {
+			continue
+		}
+		if !e.next.After(now) {
+			if e.running {
+				s.logger.Warn("Skipping refresh, previous refresh still running", "plugin_id", id)
+			} else {
+				e.running = true
+				go s.run(ctx, id, e)
+			}
+			e.next = s.nextRun(e, now)
+			if e.next.IsZero() {
+				continue
+			}
+		}
+		if earliest.IsZero() || e.next.Before(earliest) {
+			earliest = e.next
+		}
+	}
+
+	if earliest.IsZero() {
+		// Nothing left to run; wake up rarely instead of never
+		return 24 * time.Hour
+	}
+	return earliest.Sub(now)
+}
+
+func (s *Scheduler) run(ctx context.Context, pluginID string, e *entry) {
+	defer func() {
+		s.mu.Lock()
+		e.running = false
+		s.mu.Unlock()
+	}()
+
+	// The refresh function logs its own outcome
+	err := s.refresh(ctx, pluginID)
+
+	next, _ := s.NextRun(pluginID)
+	s.logger.Debug("Scheduled refresh finished", "plugin_id", pluginID, "failed", err != nil, "next_run", next)
+}
@@ -0,0 +1,107 @@
+package scheduler
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// inUTC runs cron expressions in UTC for the duration of a test
+func inUTC(t *testing.T) {
+	local := time.Local
+	time.Local = time.UTC
+	t.Cleanup(func() { time.Local = local })
+}
+
+func TestCronNext(t *testing.T) {
+	inUTC(t)
+	from := time.Date(2024, 3, 1, 10, 7, 30, 0, time.UTC) // A Friday
+
+	tests := []struct {
+		expr string
+		want []string // The next runs after from, formatted as "2006-01-02 15:04 Mon"
+	}{
+		{"*/15 * * * *", []string{"2024-03-01 10:15 Fri", "2024-03-01 10:30 Fri", "2024-03-01 10:45 Fri"}},
+		{"5/20 * * * *", []string{"2024-03-01 10:25 Fri", "2024-03-01 10:45 Fri", "2024-03-01 11:05 Fri"}},
+		{"0 6 * * mon-fri", []string{"2024-03-04 06:00 Mon", "2024-03-05 06:00 Tue", "2024-03-06 06:00 Wed"}},
+		{"30 8,20 * * *", []string{"2024-03-01 20:30 Fri", "2024-03-02 08:30 Sat", "2024-03-02 20:30 Sat"}},
+		{"0 0 * * 7", []string{"2024-03-03 00:00 Sun", "2024-03-10 00:00 Sun", "2024-03-17 00:00 Sun"}},
+		// Both day fields restricted: either one matches
+		{"0 12 15 * sat", []string{"2024-03-02 12:00 Sat", "2024-03-09 12:00 Sat", "2024-03-15 12:00 Fri"}},
+		{"0 0 29 feb *", []string{"2028-02-29 00:00 Tue", "2032-02-29 00:00 Sun"}},
+		{"@monthly", []string{"2024-04-01 00:00 Mon", "2024-05-01 00:00 Wed", "2024-06-01 00:00 Sat"}},
+		// Never matches: the zero time
+		{"0 0 30 2 *", []string{"0001-01-01 00:00 Mon"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			cron, err := ParseCron(tt.expr)
+			if err != nil {
+				t.Fatalf("ParseCron: %v", err)
+			}
+			var got []string
+			next := from
+			for range tt.want {
+				next = cron.Next(next)
+				got = append(got, next.Format("2006-01-02 15:04 Mon"))
+			}
+			if strings.Join(got, ", ") != strings.Join(tt.want, ", ") {
+				t.Errorf("got  %v\nwant %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseCronErrors(t *testing.T) {
+	tests := []struct {
+		expr string
+		want string
+	}{
+		{"* * * *", "must have 5 fields"},
+		{"60 * * * *", `invalid minute "60"`},
+		{"* 24 * * *", `invalid hour "24"`},
+		{"* * 0 * *", `invalid day of month "0"`},
+		{"* * * foo *", `invalid month "foo"`},
+		{"* * * * 8", `invalid day of week "8"`},
+		{"*/0 * * * *", `invalid minute step "0"`},
+		{"30-10 * * * *", `invalid minute range "30-10"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			_, err := ParseCron(tt.expr)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestNextRunJitter(t *testing.T) {
+	now := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		interval time.Duration
+		jitter   time.Duration
+		max      time.Duration // Latest run after now
+	}{
+		{"no jitter", time.Hour, 0, time.Hour},
+		{"jitter", time.Hour, 5 * time.Minute, time.Hour + 5*time.Minute},
+		{"capped at half the interval", 10 * time.Minute, time.Hour, 15 * time.Minute},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := New(nil, tt.jitter, nil)
+			e := &entry{schedule: Interval(tt.interval)}
+			for range 100 {
+				next := s.nextRun(e, now)
+				if next.Before(now.Add(tt.interval)) || next.After(now.Add(tt.max)) {
+					t.Fatalf("next run %v after now, want between %v and %v", next.Sub(now), tt.interval, tt.max)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,213 @@
+package server
+
+import (
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/jacobsee/modcal/internal/agenda"
+	"github.com/jacobsee/modcal/internal/feed"
+	"github.com/jacobsee/modcal/internal/ical"
+	"github.com/jacobsee/modcal/internal/jsoncal"
+	"github.com/jacobsee/modcal/internal/models"
+)
+
+// calendarFormat is an output format for a calendar
+type calendarFormat struct {
+	contentType string
+	extension   string // Non-empty if the response should be a download
+	render      func(cal *models.Calendar, r *http.Request) ([]byte, error)
+}
+
+const defaultFormat = "ics"
+
+var calendarFormats = map[string]calendarFormat{
+	"ics": {
+		contentType: "text/calendar; charset=utf-8",
+		extension:   "ics",
+		render: func(cal *models.Calendar, r *http.Request) ([]byte, error) {
+			return []byte(ical.Format(cal)), nil
+		},
+	},
+	"json": {
+		contentType: "application/json; charset=utf-8",
+		render: func(cal *models.Calendar, r *http.Request) ([]byte, error) {
+			return jsoncal.Format(cal)
+		},
+	},
+	"jcal": {
+		contentType: "application/calendar+json; charset=utf-8",
+		render: func(cal *models.Calendar, r *http.Request) ([]byte, error) {
+			return ical.FormatJCal(cal)
+		},
+	},
+	"atom": {
+		contentType: "application/atom+xml; charset=utf-8",
+		render: func(cal *models.Calendar, r *http.Request) ([]byte, error) {
+			return feed.Atom(cal, feedOptions(r))
+		},
+	},
+	"rss": {
+		contentType: "application/rss+xml; charset=utf-8",
+		render: func(cal *models.Calendar, r *http.Request) ([]byte, error) {
+			return feed.RSS(cal, feedOptions(r))
+		},
+	},
+	"html": {
+		contentType: "text/html; charset=utf-8",
+		render: func(cal *models.Calendar, r *http.Request) ([]byte, error) {
+			return agenda.Render(cal, agenda.Options{SubscribeURL: calendarURL(r, ".ics")})
+		},
+	},
+}
+
+// pathFormats maps file extensions accepted on /calendar/{name} to format names
+var pathFormats = map[string]string{
+	".ics":  "ics",
+	".json": "json",
+	".jcal": "jcal",
+	".atom": "atom",
+	".rss":  "rss",
+	".html": "html",
+}
+
+// acceptFormats maps media types in an Accept header to format names
+var acceptFormats = map[string]string{
+	"text/calendar":             "ics",
+	"application/json":          "json",
+	"application/calendar+json": "jcal",
+	"application/atom+xml":      "atom",
+	"application/rss+xml":       "rss",
+	"text/html":                 "html",
+	"application/xhtml+xml":     "html",
+}
+
+// splitFormatExtension splits a known format extension such as ".rss" off a
+// calendar name
+func splitFormatExtension(name string) (string, string) {
+	for ext, format := range pathFormats {
+		if base, ok := strings.CutSuffix(name, ext); ok && base != "" {
+			return base, format
+		}
+	}
+	return name, ""
+}
+
+// negotiateFormat picks the output format from the path extension or the
+// format query parameter, falling back to the Accept header and then to ICS.
+// It returns false if the query parameter names an unknown format.
+func negotiateFormat(r *http.Request, extFormat string) (string, bool) {
+	if extFormat != "" {
+		return extFormat, true
+	}
+	if name := r.URL.Query().Get("format"); name != "" {
+		name = strings.ToLower(name)
+		_, ok := calendarFormats[name]
+		return name, ok
+	}
+
+	for _, mediaType := range acceptedTypes(r.Header.Get("Accept")) {
+		if name, ok := acceptFormats[mediaType]; ok {
+			return name, true
+		}
+	}
+
+	return defaultFormat, true
+}
+
+// wantsHTML reports whether a request for a page that is JSON by default
+// should get HTML, from its extension, format parameter or Accept header
+func wantsHTML(r *http.Request) bool {
+	switch {
+	case strings.HasSuffix(r.URL.Path, ".html"):
+		return true
+	case strings.HasSuffix(r.URL.Path, ".json"):
+		return false
+	case r.URL.Query().Get("format") != "":
+		return strings.EqualFold(r.URL.Query().Get("format"), "html")
+	}
+
+	for _, mediaType := range acceptedTypes(r.Header.Get("Accept")) {
+		if name, ok := acceptFormats[mediaType]; ok {
+			return name == "html"
+		}
+	}
+	return false
+}
+
+// acceptedTypes returns the media types of an Accept header ordered by
+// preference. Types with q=0 are dropped.
+func acceptedTypes(header string) []string {
+	type accepted struct {
+		mediaType string
+		quality   float64
+	}
+
+	var types []accepted
+	for _, part := range strings.Split(header, ",") {
+		fields := strings.Split(part, ";")
+		mediaType := strings.ToLower(strings.TrimSpace(fields[0]))
+		if mediaType == "" {
+			continue
+		}
+
+		quality := 1.0
+		for _, param := range fields[1:] {
+			key, value, ok := strings.Cut(strings.TrimSpace(param), "=")
+			if ok && strings.EqualFold(key, "q") {
+				if q, err := strconv.ParseFloat(value, 64); err == nil {
+					quality = q
+				}
+			}
+		}
+		if quality > 0 {
+			types = append(types, accepted{mediaType, quality})
+		}
+	}
+
+	sort.SliceStable(types, func(i, j int) bool { return types[i].quality > types[j].quality })
+
+	result := make([]string, len(types))
+	for i, t := range types {
+		result[i] = t.mediaType
+	}
+	return result
+}
+
+// feedOptions derives the feed's own URL and its calendar's URL from the
+// request
+func feedOptions(r *http.Request) feed.Options {
+	self := requestURL(r)
+	return feed.Options{Self: self.String(), Link: calendarURL(r, "")}
+}
+
+// calendarURL returns the URL of the requested calendar with the given
+// format extension and without a format parameter. The rest of the query is
+// kept so authenticated links keep working.
+func calendarURL(r *http.Request, ext string) string {
+	u := requestURL(r)
+	u.Path, _ = splitFormatExtension(u.Path)
+	u.Path += ext
+
+	query := u.Query()
+	query.Del("format")
+	u.RawQuery = query.Encode()
+
+	return u.String()
+}
+
+// requestURL reconstructs the absolute URL of a request, honoring
+// X-Forwarded-Proto from reverse proxies
+func requestURL(r *http.Request) *url.URL {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	if proto := r.Header.Get("X-Forwarded-Proto"); proto != "" {
+		scheme = strings.ToLower(strings.TrimSpace(strings.Split(proto, ",")[0]))
+	}
+
+	return &url.URL{Scheme: scheme, Host: r.Host, Path: r.URL.Path, RawQuery: r.URL.RawQuery}
+}
@@ -0,0 +1,59 @@
+package server
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestSplitFormatExtension(t *testing.T) {
+	tests := []struct {
+		name, base, format string
+	}{
+		{"tv-shows", "tv-shows", ""},
+		{"tv-shows.ics", "tv-shows", "ics"},
+		{"tv-shows.jcal", "tv-shows", "jcal"},
+		{"tv-shows.rss", "tv-shows", "rss"},
+		{".json", ".json", ""},
+	}
+
+	for _, tt := range tests {
+		base, format := splitFormatExtension(tt.name)
+		if base != tt.base || format != tt.format {
+			t.Errorf("splitFormatExtension(%q) = %q, %q, want %q, %q", tt.name, base, format, tt.base, tt.format)
+		}
+	}
+}
+
+func TestNegotiateFormat(t *testing.T) {
+	tests := []struct {
+		name      string
+		query     string
+		accept    string
+		extFormat string
+		want      string
+		ok        bool
+	}{
+		{name: "default", want: "ics", ok: true},
+		{name: "extension wins", query: "format=json", accept: "text/html", extFormat: "rss", want: "rss", ok: true},
+		{name: "query", query: "format=JCal", accept: "text/html", want: "jcal", ok: true},
+		{name: "unknown query", query: "format=pdf", want: "pdf", ok: false},
+		{name: "accept", accept: "application/calendar+json", want: "jcal", ok: true},
+		{name: "accept by quality", accept: "text/html;q=0.5, application/atom+xml", want: "atom", ok: true},
+		{name: "accept skips unknown types", accept: "image/png, application/json;q=0.1", want: "json", ok: true},
+		{name: "accept without a match", accept: "*/*", want: "ics", ok: true},
+		{name: "refused type", accept: "text/html;q=0", want: "ics", ok: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/calendar/tv?"+tt.query, nil)
+			if tt.accept != "" {
+				r.Header.Set("Accept", tt.accept)
+			}
+			got, ok := negotiateFormat(r, tt.extFormat)
+			if got != tt.want || ok != tt.ok {
+				t.Errorf("negotiateFormat = %q, %v, want %q, %v", got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
@@ -0,0 +1,55 @@
+package server
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/jacobsee/modcal/internal/metrics"
+)
+
+var (
+	httpRequests = metrics.Default.NewCounterVec(
+		"modcal_http_requests_total",
+		"HTTP requests served, by route and status code.",
+		"route", "code",
+	)
+	httpDuration = metrics.Default.NewHistogramVec(
+		"modcal_http_request_duration_seconds",
+		"Time taken to serve HTTP requests, by route.",
+		metrics.DefaultBuckets,
+		"route",
+	)
+)
+
+// statusRecorder remembers the status code and size of a response
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+	bytes  int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *statusRecorder) Write(data []byte) (int, error) {
+	n, err := r.ResponseWriter.Write(data)
+	r.bytes += n
+	return n, err
+}
+
+// metricsMiddleware counts and times requests under the given route, which
+// is used as the label instead of the path so calendar names don't create
+// a series each
+func metricsMiddleware(route string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next(recorder, r)
+
+		httpRequests.Inc(route, strconv.Itoa(recorder.status))
+		httpDuration.Observe(time.Since(start).Seconds(), route)
+	}
+}
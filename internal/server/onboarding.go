@@ -0,0 +1,259 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"embed"
+	"errors"
+	"fmt"
+	"html/template"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/jacobsee/modcal/internal/plugin"
+	"github.com/jacobsee/modcal/internal/tokens"
+)
+
+//go:embed templates/*.html
+var templateFS embed.FS
+
+var authTemplate = template.Must(template.ParseFS(templateFS, "templates/auth.html"))
+
+// Flows that don't say when they expire are started over after this long
+const authTimeout = 15 * time.Minute
+
+// authSession is an authorization of a plugin instance in progress
+type authSession struct {
+	flow      *plugin.AuthFlow
+	expires   time.Time
+	cancel    context.CancelFunc // Stops polling a device flow
+	finishing bool               // Finish is running or succeeded
+	completed bool               // Finish returned for good
+	err       error
+}
+
+type authPage struct {
+	PluginID   string
+	PluginType string
+	URL        string
+	UserCode   string
+	Polling    bool
+	Completed  bool
+	Retry      bool // The code can be entered again after an error
+	Error      string
+	FormAction string
+	RestartURL string
+}
+
+// handleAuth runs the OAuth flow of a plugin instance: GET shows the current
+// step, starting a flow if none is in progress, and POST takes a pasted code
+func (s *Server) handleAuth(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	p, ok := s.calManager.Plugin(id)
+	if !ok {
+		http.Error(w, fmt.Sprintf("Plugin %s not found", id), http.StatusNotFound)
+		return
+	}
+	authorizer, ok := p.(plugin.Authorizer)
+	if !ok {
+		http.Error(w, fmt.Sprintf("Plugin %s does not use OAuth", id), http.StatusBadRequest)
+		return
+	}
+	if _, ok := s.tokens.(*tokens.FileStore); !ok {
+		http.Error(w, "tokens.path must be set in the config to save the tokens", http.StatusConflict)
+		return
+	}
+
+	page := authPage{
+		PluginID:   id,
+		PluginType: p.Name(),
+		FormAction: authURL(r, false),
+		RestartURL: authURL(r, true),
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		session := s.authSession(id)
+		if session == nil || r.URL.Query().Has("restart") || (!session.finishing && time.Now().After(session.expires)) {
+			var err error
+			if session, err = s.startAuth(r, id, authorizer); err != nil {
+				page.Error = err.Error()
+				s.renderAuth(w, http.StatusBadGateway, page)
+				return
+			}
+		}
+		s.renderAuth(w, http.StatusOK, s.sessionPage(page, session))
+
+	case http.MethodPost:
+		session := s.authSession(id)
+		if session == nil || session.flow.Device() {
+			page.Error = "No authorization is waiting for a code."
+			s.renderAuth(w, http.StatusConflict, page)
+			return
+		}
+		code, err := session.flow.Code(r.FormValue("code"))
+		if err != nil {
+			page.Error = err.Error()
+			s.renderAuth(w, http.StatusBadRequest, page)
+			return
+		}
+		s.finishAuth(r.Context(), id, session, code)
+		http.Redirect(w, r, authURL(r, false), http.StatusSeeOther)
+
+	default:
+		w.Header().Set("Allow", "GET, POST")
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// handleAuthCallback receives the browser from providers that redirect back
+// with a code. It needs no API key since the provider drops the query; the
+// state ties the request to a flow started by an authenticated user.
+func (s *Server) handleAuthCallback(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	page := authPage{PluginID: id}
+	if p, ok := s.calManager.Plugin(id); ok {
+		page.PluginType = p.Name()
+	}
+
+	session := s.authSession(id)
+	state := r.URL.Query().Get("state")
+	if session == nil || session.flow.State == "" || state != session.flow.State {
+		page.Error = "This authorization is unknown or has expired. Start again from the authorization page."
+		s.renderAuth(w, http.StatusBadRequest, page)
+		return
+	}
+
+	code, err := session.flow.Code(requestURL(r).String())
+	if err != nil {
+		page.Error = err.Error()
+		s.renderAuth(w, http.StatusBadRequest, page)
+		return
+	}
+	s.finishAuth(r.Context(), id, session, code)
+	s.renderAuth(w, http.StatusOK, s.sessionPage(page, session))
+}
+
+// authSession returns the flow in progress for a plugin instance, if any
+func (s *Server) authSession(id string) *authSession {
+	s.authMu.Lock()
+	defer s.authMu.Unlock()
+	return s.authSessions[id]
+}
+
+// startAuth starts a new flow for a plugin instance, replacing any other.
+// Device flows are polled in the background until they complete or are
+// replaced.
+func (s *Server) startAuth(r *http.Request, id string, authorizer plugin.Authorizer) (*authSession, error) {
+	callback := requestURL(r)
+	callback.Path += "/callback"
+	callback.RawQuery = ""
+
+	flow, err := authorizer.StartAuth(r.Context(), callback.String())
+	if err != nil {
+		s.logger.Warn("Failed to start authorization", "plugin_id", id, "error", err)
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	session := &authSession{flow: flow, expires: flow.Expires, cancel: cancel}
+	if session.expires.IsZero() {
+		session.expires = time.Now().Add(authTimeout)
+	}
+
+	s.authMu.Lock()
+	if previous := s.authSessions[id]; previous != nil {
+		previous.cancel()
+	}
+	s.authSessions[id] = session
+	s.authMu.Unlock()
+	s.logger.Info("Started authorization", "plugin_id", id)
+
+	if flow.Device() {
+		go s.finishAuth(ctx, id, session, "")
+	}
+	return session, nil
+}
+
+// finishAuth completes a flow once and refreshes the plugin's events with
+// the new token. A code that fails can be corrected and submitted again.
+func (s *Server) finishAuth(ctx context.Context, id string, session *authSession, code string) {
+	s.authMu.Lock()
+	if session.finishing {
+		s.authMu.Unlock()
+		return
+	}
+	session.finishing = true
+	s.authMu.Unlock()
+
+	err := session.flow.Finish(ctx, code)
+
+	s.authMu.Lock()
+	session.err = err
+	if err != nil && !errors.Is(err, context.Canceled) && !session.flow.Device() {
+		session.finishing = false
+	} else {
+		session.completed = true
+	}
+	s.authMu.Unlock()
+
+	if errors.Is(err, context.Canceled) {
+		s.logger.Debug("Authorization replaced", "plugin_id", id)
+		return
+	}
+	if err != nil {
+		s.logger.Warn("Authorization failed", "plugin_id", id, "error", err)
+		return
+	}
+	s.logger.Info("Authorized plugin", "plugin_id", id)
+	go func() {
+		if err := s.calManager.RefreshPlugin(context.Background(), id); err != nil {
+			s.logger.Debug("Refresh after authorization failed", "plugin_id", id, "error", err)
+		}
+	}()
+}
+
+// sessionPage fills in the current step of a flow
+func (s *Server) sessionPage(page authPage, session *authSession) authPage {
+	s.authMu.Lock()
+	defer s.authMu.Unlock()
+
+	page.URL = session.flow.URL
+	page.UserCode = session.flow.UserCode
+	page.Completed = session.completed
+	page.Polling = session.flow.Device() && !session.completed
+	page.Retry = page.FormAction != "" && !session.flow.Device() && !session.finishing
+	if session.err != nil {
+		page.Error = session.err.Error()
+	}
+	return page
+}
+
+func (s *Server) renderAuth(w http.ResponseWriter, status int, page authPage) {
+	var buf bytes.Buffer
+	if err := authTemplate.Execute(&buf, page); err != nil {
+		s.logger.Error("Failed to render authorization page", "error", err)
+		http.Error(w, "Failed to render authorization page", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	if _, err := w.Write(buf.Bytes()); err != nil {
+		s.logger.Debug("Failed to write authorization page", "error", err)
+	}
+}
+
+// authURL returns the path of the authorization page with the request's
+// query, so the API key is kept, optionally asking to start over
+func authURL(r *http.Request, restart bool) string {
+	query := r.URL.Query()
+	query.Del("restart")
+	if restart {
+		query.Set("restart", "1")
+	}
+	u := url.URL{Path: r.URL.Path, RawQuery: query.Encode()}
+	return u.String()
+}
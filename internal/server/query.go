@@ -0,0 +1,98 @@
+package server
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jacobsee/modcal/internal/calendar"
+)
+
+// Layouts accepted for the from and to parameters. Values without a zone are
+// in the calendar's time zone.
+var queryTimeLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006-01-02T15:04",
+}
+
+const queryDateLayout = "2006-01-02"
+
+// parseQuery reads the event filters of a calendar request:
+//
+//	from, to          RFC 3339 time, local time or date; a date as to includes that day
+//	days              number of days after from (default: now)
+//	category          keep events in any of these categories (repeatable, comma-separated)
+//	exclude-category  drop events in any of these categories
+//	q                 text to search for in summaries and descriptions
+func parseQuery(values url.Values, loc *time.Location, now time.Time) (calendar.Query, error) {
+	var q calendar.Query
+	var err error
+
+	if value := values.Get("from"); value != "" {
+		if q.From, err = parseQueryTime(value, loc, false); err != nil {
+			return q, fmt.Errorf("invalid from: %w", err)
+		}
+	}
+	if value := values.Get("to"); value != "" {
+		if q.To, err = parseQueryTime(value, loc, true); err != nil {
+			return q, fmt.Errorf("invalid to: %w", err)
+		}
+	}
+
+	if value := values.Get("days"); value != "" {
+		days, err := strconv.Atoi(value)
+		if err != nil || days <= 0 {
+			return q, fmt.Errorf("invalid days: %q is not a positive number", value)
+		}
+		if !q.To.IsZero() {
+			return q, fmt.Errorf("days and to cannot be combined")
+		}
+		if q.From.IsZero() {
+			q.From = now
+		}
+		q.To = q.From.AddDate(0, 0, days)
+	}
+
+	if !q.From.IsZero() && !q.To.IsZero() && !q.From.Before(q.To) {
+		return q, fmt.Errorf("from must be before to")
+	}
+
+	q.Categories = listParam(values["category"])
+	q.ExcludeCategories = listParam(values["exclude-category"])
+	q.Text = strings.TrimSpace(values.Get("q"))
+
+	return q, nil
+}
+
+// parseQueryTime parses a from or to value. A date means the start of that
+// day, or the end of it if endOfDay is set.
+func parseQueryTime(value string, loc *time.Location, endOfDay bool) (time.Time, error) {
+	if t, err := time.ParseInLocation(queryDateLayout, value, loc); err == nil {
+		if endOfDay {
+			t = t.AddDate(0, 0, 1)
+		}
+		return t, nil
+	}
+	for _, layout := range queryTimeLayouts {
+		if t, err := time.ParseInLocation(layout, value, loc); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("%q is not a date or time", value)
+}
+
+// listParam flattens repeated and comma-separated parameter values
+func listParam(values []string) []string {
+	var result []string
+	for _, value := range values {
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				result = append(result, item)
+			}
+		}
+	}
+	return result
+}
@@ -0,0 +1,91 @@
+package server
+
+import (
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jacobsee/modcal/internal/calendar"
+)
+
+func TestParseQuery(t *testing.T) {
+	loc, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2024, 3, 5, 12, 0, 0, 0, loc)
+
+	tests := []struct {
+		query string
+		want  calendar.Query
+		err   string
+	}{
+		{query: "", want: calendar.Query{}},
+		{
+			query: "from=2024-03-01&to=2024-03-31",
+			want: calendar.Query{
+				From: time.Date(2024, 3, 1, 0, 0, 0, 0, loc),
+				To:   time.Date(2024, 4, 1, 0, 0, 0, 0, loc),
+			},
+		},
+		{
+			query: "from=2024-03-01T18:30&to=2024-03-02T01:00:00Z",
+			want: calendar.Query{
+				From: time.Date(2024, 3, 1, 18, 30, 0, 0, loc),
+				To:   time.Date(2024, 3, 2, 1, 0, 0, 0, time.UTC),
+			},
+		},
+		{
+			query: "days=7",
+			want:  calendar.Query{From: now, To: now.AddDate(0, 0, 7)},
+		},
+		{
+			query: "from=2024-03-09&days=2",
+			want: calendar.Query{
+				From: time.Date(2024, 3, 9, 0, 0, 0, 0, loc),
+				To:   time.Date(2024, 3, 11, 0, 0, 0, 0, loc),
+			},
+		},
+		{
+			query: "category=tv,anime&category=+movie+&exclude-category=sonarr,&q=+news+",
+			want: calendar.Query{
+				Categories:        []string{"tv", "anime", "movie"},
+				ExcludeCategories: []string{"sonarr"},
+				Text:              "news",
+			},
+		},
+		{query: "from=yesterday", err: `invalid from: "yesterday" is not a date or time`},
+		{query: "to=2024-13-01", err: "invalid to"},
+		{query: "days=0", err: `invalid days: "0" is not a positive number`},
+		{query: "days=7&to=2024-03-31", err: "days and to cannot be combined"},
+		{query: "from=2024-03-31&to=2024-03-01", err: "from must be before to"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			values, err := url.ParseQuery(tt.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := parseQuery(values, loc, now)
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Errorf("error = %v, want %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseQuery: %v", err)
+			}
+			if !got.From.Equal(tt.want.From) || !got.To.Equal(tt.want.To) {
+				t.Errorf("range = %v - %v, want %v - %v", got.From, got.To, tt.want.From, tt.want.To)
+			}
+			got.From, got.To, tt.want.From, tt.want.To = time.Time{}, time.Time{}, time.Time{}, time.Time{}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
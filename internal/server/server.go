@@ -2,30 +2,42 @@ package server
 
 import (
 	"fmt"
-	"log"
+	"log/slog"
 	"net/http"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/jacobsee/modcal/internal/auth"
 	"github.com/jacobsee/modcal/internal/calendar"
-	"github.com/jacobsee/modcal/internal/ical"
+	"github.com/jacobsee/modcal/internal/metrics"
+	"github.com/jacobsee/modcal/internal/status"
+	"github.com/jacobsee/modcal/internal/tokens"
 )
 
 // Server represents the HTTP server
 type Server struct {
 	calManager *calendar.Manager
 	auth       auth.Authenticator
+	tokens     tokens.Store
 	host       string
 	port       int
+	logger     *slog.Logger
+
+	authMu       sync.Mutex
+	authSessions map[string]*authSession // OAuth flows by plugin ID
 }
 
 // New creates a new server instance
-func New(calManager *calendar.Manager, authenticator auth.Authenticator, host string, port int) *Server {
+func New(calManager *calendar.Manager, authenticator auth.Authenticator, tokenStore tokens.Store, host string, port int, logger *slog.Logger) *Server {
 	return &Server{
-		calManager: calManager,
-		auth:       authenticator,
-		host:       host,
-		port:       port,
+		calManager:   calManager,
+		auth:         authenticator,
+		tokens:       tokenStore,
+		host:         host,
+		port:         port,
+		logger:       logger,
+		authSessions: make(map[string]*authSession),
 	}
 }
 
@@ -33,13 +45,50 @@ func New(calManager *calendar.Manager, authenticator auth.Authenticator, host st
 func (s *Server) Start() error {
 	mux := http.NewServeMux()
 
-	mux.HandleFunc("/calendars", s.authMiddleware(s.handleListCalendars))
-	mux.HandleFunc("/calendar/", s.authMiddleware(s.handleGetCalendar))
+	s.handle(mux, "/calendars", s.authMiddleware(s.handleListCalendars))
+	s.handle(mux, "/calendar/", s.authMiddleware(s.handleGetCalendar))
+	s.handle(mux, "/status", s.authMiddleware(s.handleStatus))
+	s.handle(mux, "/status.json", s.authMiddleware(s.handleStatus))
+	s.handle(mux, "/status.html", s.authMiddleware(s.handleStatus))
+	s.handle(mux, "/metrics", s.authMiddleware(metrics.Default.Handler().ServeHTTP))
+	// Anyone who can open the authorization pages can replace the tokens of
+	// a plugin, so they need an API key
+	if _, ok := s.auth.(*auth.APIKeyAuth); ok {
+		s.handle(mux, "/auth/{id}", s.authMiddleware(s.handleAuth))
+		s.handle(mux, "/auth/{id}/callback", s.handleAuthCallback)
+	} else {
+		s.logger.Info("Authorization pages are disabled without API key authentication")
+	}
 
 	addr := fmt.Sprintf("%s:%d", s.host, s.port)
-	log.Printf("Starting server on %s", addr)
+	s.logger.Info("Starting server", "addr", addr)
+
+	return http.ListenAndServe(addr, s.logMiddleware(mux))
+}
 
-	return http.ListenAndServe(addr, mux)
+// handle registers a handler with request metrics labeled by its pattern
+func (s *Server) handle(mux *http.ServeMux, pattern string, handler http.HandlerFunc) {
+	mux.HandleFunc(pattern, metricsMiddleware(pattern, handler))
+}
+
+// logMiddleware logs every request. The query is left out since it may
+// contain the API key.
+func (s *Server) logMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(recorder, r)
+
+		s.logger.Info("Request",
+			"method", r.Method,
+			"path", r.URL.Path,
+			"status", recorder.status,
+			"bytes", recorder.bytes,
+			"duration", time.Since(start),
+			"remote", r.RemoteAddr,
+			"user_agent", r.UserAgent(),
+		)
+	})
 }
 
 func (s *Server) authMiddleware(next http.HandlerFunc) http.HandlerFunc {
@@ -62,23 +111,76 @@ func (s *Server) handleListCalendars(w http.ResponseWriter, r *http.Request) {
 }
 
 func (s *Server) handleGetCalendar(w http.ResponseWriter, r *http.Request) {
-	name := strings.TrimPrefix(r.URL.Path, "/calendar/")
+	name, extFormat := splitFormatExtension(strings.TrimPrefix(r.URL.Path, "/calendar/"))
 	if name == "" {
 		http.Error(w, "Calendar name required", http.StatusBadRequest)
 		return
 	}
 
-	cal, err := s.calManager.GetCalendar(name)
+	loc, err := s.calManager.Location(name)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	query, err := parseQuery(r.URL.Query(), loc, time.Now())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	cal, err := s.calManager.QueryCalendar(name, query)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusNotFound)
 		return
 	}
 
-	icalData := ical.Format(cal)
+	formatName, ok := negotiateFormat(r, extFormat)
+	if !ok {
+		http.Error(w, fmt.Sprintf("Unknown format %q", formatName), http.StatusBadRequest)
+		return
+	}
+	format := calendarFormats[formatName]
+
+	data, err := format.render(cal, r)
+	if err != nil {
+		s.logger.Error("Failed to render calendar", "calendar", name, "format", formatName, "error", err)
+		http.Error(w, "Failed to render calendar", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", format.contentType)
+	w.Header().Set("Vary", "Accept")
+	if format.extension != "" {
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%s.%s", name, format.extension))
+	}
+	if _, err := w.Write(data); err != nil {
+		s.logger.Debug("Failed to write calendar response", "calendar", name, "error", err)
+	}
+}
+
+// handleStatus reports the refresh status of every plugin instance, as an
+// HTML page for browsers and JSON otherwise
+func (s *Server) handleStatus(w http.ResponseWriter, r *http.Request) {
+	statuses := s.calManager.PluginStatuses()
+
+	var data []byte
+	var err error
+	if wantsHTML(r) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		data, err = status.HTML(statuses, time.Now())
+	} else {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		data, err = status.JSON(statuses)
+	}
+	if err != nil {
+		s.logger.Error("Failed to render status", "error", err)
+		http.Error(w, "Failed to render status", http.StatusInternalServerError)
+		return
+	}
 
-	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
-	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%s.ics", name))
-	if _, err := w.Write([]byte(icalData)); err != nil {
-		log.Printf("Error writing calendar response: %v", err)
+	w.Header().Set("Vary", "Accept")
+	if _, err := w.Write(data); err != nil {
+		s.logger.Debug("Failed to write status response", "error", err)
 	}
 }
@@ -0,0 +1,146 @@
+package status
+
+import (
+	"bytes"
+	"embed"
+	"encoding/json"
+	"html/template"
+	"strconv"
+	"time"
+
+	"github.com/jacobsee/modcal/internal/calendar"
+)
+
+//go:embed templates/*.html
+var templateFS embed.FS
+
+var statusTemplate = template.Must(template.ParseFS(templateFS, "templates/status.html"))
+
+// Report is the JSON representation of the plugin statuses
+type Report struct {
+	Healthy bool           `json:"healthy"`
+	Plugins []PluginReport `json:"plugins"`
+}
+
+// PluginReport is the JSON representation of one plugin instance's status.
+// Times are omitted if the event never happened.
+type PluginReport struct {
+	ID          string     `json:"id"`
+	Type        string     `json:"type"`
+	Healthy     bool       `json:"healthy"`
+	LastAttempt *time.Time `json:"lastAttempt,omitempty"`
+	LastSuccess *time.Time `json:"lastSuccess,omitempty"`
+	LastError   string     `json:"lastError,omitempty"`
+	LastErrorAt *time.Time `json:"lastErrorAt,omitempty"`
+	Failures    int        `json:"failures"`
+	EventCount  int        `json:"eventCount"`
+	DurationMS  int64      `json:"durationMs"`
+}
+
+// NewReport summarizes plugin statuses. The report is healthy if no plugin
+// instance's last refresh failed.
+func NewReport(statuses []calendar.PluginStatus) Report {
+	report := Report{Healthy: true, Plugins: make([]PluginReport, 0, len(statuses))}
+	for _, s := range statuses {
+		report.Plugins = append(report.Plugins, PluginReport{
+			ID:          s.ID,
+			Type:        s.Type,
+			Healthy:     !s.Failing(),
+			LastAttempt: optionalTime(s.LastAttempt),
+			LastSuccess: optionalTime(s.LastSuccess),
+			LastError:   s.LastError,
+			LastErrorAt: optionalTime(s.LastErrorAt),
+			Failures:    s.Failures,
+			EventCount:  s.EventCount,
+			DurationMS:  s.Duration.Milliseconds(),
+		})
+		if s.Failing() {
+			report.Healthy = false
+		}
+	}
+	return report
+}
+
+func optionalTime(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
+
+// JSON renders the plugin statuses as JSON
+func JSON(statuses []calendar.PluginStatus) ([]byte, error) {
+	return json.MarshalIndent(NewReport(statuses), "", "  ")
+}
+
+type page struct {
+	Healthy bool
+	Plugins []row
+}
+
+type row struct {
+	ID          string
+	Type        string
+	Healthy     bool
+	Pending     bool // Never refreshed
+	LastAttempt string
+	LastSuccess string
+	LastError   string
+	LastErrorAt string
+	Failures    int
+	EventCount  int
+	Duration    string
+}
+
+// HTML renders the plugin statuses as a page, with times relative to now
+func HTML(statuses []calendar.PluginStatus, now time.Time) ([]byte, error) {
+	report := NewReport(statuses)
+	p := page{Healthy: report.Healthy}
+	for _, s := range statuses {
+		p.Plugins = append(p.Plugins, row{
+			ID:          s.ID,
+			Type:        s.Type,
+			Healthy:     !s.Failing(),
+			Pending:     s.LastAttempt.IsZero() && s.LastSuccess.IsZero(),
+			LastAttempt: ago(s.LastAttempt, now),
+			LastSuccess: ago(s.LastSuccess, now),
+			LastError:   s.LastError,
+			LastErrorAt: ago(s.LastErrorAt, now),
+			Failures:    s.Failures,
+			EventCount:  s.EventCount,
+			Duration:    s.Duration.Round(time.Millisecond).String(),
+		})
+	}
+
+	var buf bytes.Buffer
+	if err := statusTemplate.Execute(&buf, p); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// ago describes how long before now t was, e.g. "3 days ago"
+func ago(t, now time.Time) string {
+	if t.IsZero() {
+		return "never"
+	}
+
+	d := now.Sub(t)
+	switch {
+	case d < time.Minute:
+		return "just now"
+	case d < time.Hour:
+		return plural(int(d/time.Minute), "minute") + " ago"
+	case d < 24*time.Hour:
+		return plural(int(d/time.Hour), "hour") + " ago"
+	default:
+		return plural(int(d/(24*time.Hour)), "day") + " ago"
+	}
+}
+
+func plural(n int, unit string) string {
+	if n == 1 {
+		return "1 " + unit
+	}
+	return strconv.Itoa(n) + " " + unit + "s"
+}
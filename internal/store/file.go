@@ -0,0 +1,94 @@
+package store
+
+import (
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+)
+
+// FileStore keeps one gob-encoded snapshot file per plugin instance in a
+// directory, along with the event stamps
+type FileStore struct {
+	dir string
+}
+
+// stampsFile holds the event stamps. Snapshot files end in .gob, so no
+// plugin ID maps to it.
+const stampsFile = "events.stamps"
+
+// NewFileStore creates a file store in dir, creating the directory if needed
+func NewFileStore(dir string) (*FileStore, error) {
+	if dir == "" {
+		return nil, fmt.Errorf("storage path is required")
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create storage directory: %w", err)
+	}
+	return &FileStore{dir: dir}, nil
+}
+
+// path returns the snapshot file of a plugin instance. IDs are escaped so
+// any ID maps to a file name in the store's directory.
+func (s *FileStore) path(pluginID string) string {
+	return filepath.Join(s.dir, url.PathEscape(pluginID)+".gob")
+}
+
+func (s *FileStore) Load(pluginID string) (Snapshot, bool, error) {
+	var snapshot Snapshot
+	ok, err := s.read(s.path(pluginID), &snapshot)
+	return snapshot, ok, err
+}
+
+func (s *FileStore) Save(pluginID string, snapshot Snapshot) error {
+	return s.write(s.path(pluginID), snapshot)
+}
+
+func (s *FileStore) LoadStamps() (map[string]Stamp, error) {
+	var stamps map[string]Stamp
+	_, err := s.read(filepath.Join(s.dir, stampsFile), &stamps)
+	return stamps, err
+}
+
+func (s *FileStore) SaveStamps(stamps map[string]Stamp) error {
+	return s.write(filepath.Join(s.dir, stampsFile), stamps)
+}
+
+// read decodes a gob file into value. ok is false if the file doesn't exist.
+func (s *FileStore) read(path string, value any) (bool, error) {
+	file, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	defer file.Close()
+
+	if err := gob.NewDecoder(file).Decode(value); err != nil {
+		return false, fmt.Errorf("failed to decode %s: %w", file.Name(), err)
+	}
+	return true, nil
+}
+
+// write replaces a gob file. It writes to a temporary file first so a crash
+// never leaves a partial file behind.
+func (s *FileStore) write(path string, value any) error {
+	tmp, err := os.CreateTemp(s.dir, ".snapshot-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := gob.NewEncoder(tmp).Encode(value); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to encode %s: %w", filepath.Base(path), err)
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
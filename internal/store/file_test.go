@@ -0,0 +1,107 @@
+package store
+
+import (
+	"crypto/sha256"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/jacobsee/modcal/internal/models"
+)
+
+func TestFileStoreRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	start := time.Date(2024, 3, 5, 20, 0, 0, 0, time.UTC)
+	snapshot := Snapshot{
+		Events: []models.Event{{
+			UID: "trakt-1", Summary: "Severance - S2E1", Source: "trakt/watched",
+			StartTime: start, EndTime: start.Add(time.Hour),
+			Categories: []string{"tv"},
+			Fields:     map[string]interface{}{"show": "Severance", "episode": 1, "aired": start},
+			Recurrence: &models.Recurrence{Rule: "FREQ=WEEKLY", ExDates: []time.Time{start.AddDate(0, 0, 7)}},
+		}},
+		FetchedAt: start,
+	}
+	stamps := map[string]Stamp{
+		"trakt-1": {Hash: sha256.Sum256([]byte("a")), Created: start, LastModified: start, Sequence: 2, LastSeen: start},
+	}
+
+	s, err := NewFileStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Save("trakt/watched", snapshot); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	if err := s.SaveStamps(stamps); err != nil {
+		t.Fatalf("SaveStamps: %v", err)
+	}
+
+	// A new store on the same directory sees what the first one saved
+	reopened, err := NewFileStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, ok, err := reopened.Load("trakt/watched")
+	if err != nil || !ok {
+		t.Fatalf("Load = %v, %v", ok, err)
+	}
+	if !reflect.DeepEqual(got, snapshot) {
+		t.Errorf("snapshot = %+v, want %+v", got, snapshot)
+	}
+	gotStamps, err := reopened.LoadStamps()
+	if err != nil {
+		t.Fatalf("LoadStamps: %v", err)
+	}
+	if !reflect.DeepEqual(gotStamps, stamps) {
+		t.Errorf("stamps = %+v, want %+v", gotStamps, stamps)
+	}
+
+	// Only the escaped snapshot and the stamps are left, no temporary files
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	if want := []string{stampsFile, "trakt%2Fwatched.gob"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("files = %q, want %q", names, want)
+	}
+}
+
+func TestFileStoreMissing(t *testing.T) {
+	s, err := NewFileStore(filepath.Join(t.TempDir(), "new"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok, err := s.Load("trakt"); ok || err != nil {
+		t.Errorf("Load = %v, %v, want no snapshot and no error", ok, err)
+	}
+	if stamps, err := s.LoadStamps(); stamps != nil || err != nil {
+		t.Errorf("LoadStamps = %v, %v, want nil", stamps, err)
+	}
+}
+
+func TestFileStoreCorrupt(t *testing.T) {
+	dir := t.TempDir()
+	s, err := NewFileStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"trakt.gob", stampsFile} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("not gob"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if _, ok, err := s.Load("trakt"); ok || err == nil {
+		t.Errorf("Load = %v, %v, want an error", ok, err)
+	}
+	if _, err := s.LoadStamps(); err == nil {
+		t.Error("LoadStamps of a corrupt file succeeded")
+	}
+}
@@ -0,0 +1,79 @@
+package store
+
+import (
+	"crypto/sha256"
+	"encoding/gob"
+	"fmt"
+	"time"
+
+	"github.com/jacobsee/modcal/internal/models"
+)
+
+func init() {
+	// Event fields hold times, which gob needs to know about to decode them
+	// from an interface value
+	gob.Register(time.Time{})
+}
+
+// Snapshot is the last successful fetch of a plugin instance
+type Snapshot struct {
+	Events    []models.Event
+	FetchedAt time.Time
+}
+
+// Stamp is the revision history of one served event, so CREATED,
+// LAST-MODIFIED and SEQUENCE survive restarts
+type Stamp struct {
+	Hash         [sha256.Size]byte // Content the revision fields belong to
+	Created      time.Time
+	LastModified time.Time
+	Sequence     int
+	LastSeen     time.Time
+}
+
+// Store persists the events of plugin instances across restarts
+type Store interface {
+	// Load returns the snapshot saved for a plugin instance. ok is false if
+	// there is none.
+	Load(pluginID string) (snapshot Snapshot, ok bool, err error)
+
+	// Save replaces the snapshot of a plugin instance
+	Save(pluginID string, snapshot Snapshot) error
+
+	// LoadStamps returns the saved event stamps by key, nil if there are none
+	LoadStamps() (map[string]Stamp, error)
+
+	// SaveStamps replaces the saved event stamps
+	SaveStamps(stamps map[string]Stamp) error
+}
+
+// NoStore is a store that keeps nothing, so events only live in memory
+type NoStore struct{}
+
+func (n *NoStore) Load(pluginID string) (Snapshot, bool, error) {
+	return Snapshot{}, false, nil
+}
+
+func (n *NoStore) Save(pluginID string, snapshot Snapshot) error {
+	return nil
+}
+
+func (n *NoStore) LoadStamps() (map[string]Stamp, error) {
+	return nil, nil
+}
+
+func (n *NoStore) SaveStamps(stamps map[string]Stamp) error {
+	return nil
+}
+
+// NewStore creates a store based on type and config
+func NewStore(storeType, path string) (Store, error) {
+	switch storeType {
+	case "", "memory":
+		return &NoStore{}, nil
+	case "file":
+		return NewFileStore(path)
+	default:
+		return nil, fmt.Errorf("unknown storage type %q", storeType)
+	}
+}
@@ -0,0 +1,216 @@
+package tokens
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Tokens are refreshed this long before they expire
+const refreshMargin = time.Hour
+
+// ErrNoToken is returned for plugin instances that have no access token yet
+var ErrNoToken = errors.New("not authorized: no access token configured or stored")
+
+// RefreshFunc exchanges a refresh token for a new token
+type RefreshFunc func(ctx context.Context, refreshToken string) (Token, error)
+
+// Source hands out the access token of a plugin instance, refreshing it when
+// it nears expiry or is rejected and persisting the result in a store
+type Source struct {
+	mu      sync.Mutex
+	id      string
+	store   Store
+	refresh RefreshFunc
+	logger  *slog.Logger
+	token   Token
+	stored  string // Access token last read from or written to the store
+}
+
+// NewSource returns the token source of a plugin instance. configured is the
+// token from the instance config; a stored token takes precedence if it was
+// refreshed from the same configured token, or if none is configured. Without
+// either, the source has no token until Set is called. refresh may be nil if
+// the plugin cannot refresh tokens. Tokens are only kept in memory if store
+// is nil.
+func NewSource(store Store, pluginID string, configured Token, refresh RefreshFunc, logger *slog.Logger) (*Source, error) {
+	if store == nil {
+		store = NewMemoryStore()
+	}
+	s := &Source{id: pluginID, store: store, refresh: refresh, logger: logger}
+
+	stored, ok := store.Get(pluginID)
+	switch {
+	case configured.AccessToken == "" && ok:
+		s.token = stored
+		s.stored = stored.AccessToken
+	case configured.AccessToken == "":
+		// Not authorized yet
+	case ok && stored.Origin == origin(configured.AccessToken):
+		s.token = stored
+		s.stored = stored.AccessToken
+	default:
+		s.token = configured
+		s.token.Origin = origin(configured.AccessToken)
+		if s.token.Expiry.IsZero() {
+			s.token.Expiry = jwtExpiry(configured.AccessToken)
+		}
+		if err := store.Put(pluginID, s.token); err != nil {
+			return nil, fmt.Errorf("failed to store token: %w", err)
+		}
+		s.stored = s.token.AccessToken
+	}
+
+	return s, nil
+}
+
+// origin identifies a configured access token without storing it again
+func origin(accessToken string) string {
+	hash := sha256.Sum256([]byte(accessToken))
+	return hex.EncodeToString(hash[:8])
+}
+
+// jwtExpiry reads the expiry of tokens that are JWTs, as MyAnimeList and
+// AniList tokens are. It returns the zero time for other tokens.
+func jwtExpiry(token string) time.Time {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return time.Time{}
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return time.Time{}
+	}
+	var claims struct {
+		Exp float64 `json:"exp"`
+	}
+	if err := json.Unmarshal(payload, &claims); err != nil || claims.Exp == 0 {
+		return time.Time{}
+	}
+	return time.Unix(int64(claims.Exp), 0)
+}
+
+// Set replaces the token, after the instance was authorized again. The
+// token takes precedence over the configured one until that changes.
+func (s *Source) Set(token Token) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if token.Expiry.IsZero() {
+		token.Expiry = jwtExpiry(token.AccessToken)
+	}
+	token.Origin = s.token.Origin
+	if err := s.store.Put(s.id, token); err != nil {
+		return fmt.Errorf("failed to store token: %w", err)
+	}
+	s.token = token
+	s.stored = token.AccessToken
+	return nil
+}
+
+// reload picks up a token stored by another process, such as "modcal auth"
+// next to a running server. Stored tokens of another configured token are
+// ignored, as in NewSource. s.mu must be held.
+func (s *Source) reload() {
+	stored, ok := s.store.Get(s.id)
+	if !ok || stored.AccessToken == s.stored || stored.Origin != s.token.Origin {
+		return
+	}
+	s.token = stored
+	s.stored = stored.AccessToken
+	s.logger.Info("Loaded token stored by another process")
+}
+
+// CanRefresh reports whether the token can be refreshed
+func (s *Source) CanRefresh() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.reload()
+	return s.refresh != nil && s.token.RefreshToken != ""
+}
+
+// AccessToken returns the current access token, refreshing it first if it
+// expires soon. If that refresh fails, the old token is returned unless it
+// has already expired.
+func (s *Source) AccessToken(ctx context.Context) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.reload()
+	if s.token.AccessToken == "" {
+		return "", ErrNoToken
+	}
+	expiry := s.token.Expiry
+	if expiry.IsZero() || time.Until(expiry) > refreshMargin {
+		return s.token.AccessToken, nil
+	}
+
+	if s.refresh == nil || s.token.RefreshToken == "" {
+		if time.Now().After(expiry) {
+			return "", fmt.Errorf("access token expired at %s and cannot be refreshed, authorize again", expiry.Format(time.RFC3339))
+		}
+		s.logger.Warn("Access token expires soon and cannot be refreshed", "expiry", expiry)
+		return s.token.AccessToken, nil
+	}
+
+	if err := s.refreshLocked(ctx); err != nil {
+		if time.Now().After(expiry) {
+			return "", err
+		}
+		s.logger.Warn("Failed to refresh access token before expiry", "expiry", expiry, "error", err)
+	}
+	return s.token.AccessToken, nil
+}
+
+// Refresh replaces an access token the API rejected. If the token was
+// already replaced in the meantime, the new one is returned as is.
+func (s *Source) Refresh(ctx context.Context, rejected string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.reload()
+	if s.token.AccessToken != rejected {
+		return s.token.AccessToken, nil
+	}
+	if s.refresh == nil || s.token.RefreshToken == "" {
+		return "", fmt.Errorf("access token was rejected and cannot be refreshed, authorize again")
+	}
+	if err := s.refreshLocked(ctx); err != nil {
+		return "", err
+	}
+	return s.token.AccessToken, nil
+}
+
+// refreshLocked refreshes and stores the token. s.mu must be held.
+func (s *Source) refreshLocked(ctx context.Context) error {
+	token, err := s.refresh(ctx, s.token.RefreshToken)
+	if err != nil {
+		return fmt.Errorf("failed to refresh access token: %w", err)
+	}
+	if token.RefreshToken == "" {
+		token.RefreshToken = s.token.RefreshToken
+	}
+	if token.Expiry.IsZero() {
+		token.Expiry = jwtExpiry(token.AccessToken)
+	}
+	token.Origin = s.token.Origin
+	s.token = token
+	s.logger.Info("Refreshed access token", "expiry", token.Expiry)
+
+	// The new token is used even if it can't be stored; the next restart
+	// then falls back to the configured one
+	if err := s.store.Put(s.id, token); err != nil {
+		s.logger.Error("Failed to store refreshed token", "error", err)
+		return nil
+	}
+	s.stored = token.AccessToken
+	return nil
+}
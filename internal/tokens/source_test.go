@@ -0,0 +1,178 @@
+package tokens
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+)
+
+var discard = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+// refresher hands out numbered tokens and counts its calls
+type refresher struct {
+	calls int
+	err   error
+}
+
+func (r *refresher) refresh(ctx context.Context, refreshToken string) (Token, error) {
+	r.calls++
+	if r.err != nil {
+		return Token{}, r.err
+	}
+	return Token{AccessToken: "refreshed-" + refreshToken, Expiry: time.Now().Add(24 * time.Hour)}, nil
+}
+
+func TestSourceStoredTokenPrecedence(t *testing.T) {
+	store := NewMemoryStore()
+	configured := Token{AccessToken: "configured", RefreshToken: "r1"}
+	r := &refresher{}
+
+	source, err := NewSource(store, "trakt", configured, r.refresh, discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := source.Refresh(context.Background(), "configured"); err != nil {
+		t.Fatal(err)
+	}
+
+	// After a restart with the same config, the refreshed token is used
+	source, err = NewSource(store, "trakt", configured, r.refresh, discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token, _ := source.AccessToken(context.Background()); token != "refreshed-r1" {
+		t.Errorf("AccessToken = %q, want the stored refreshed-r1", token)
+	}
+
+	// A new token in the config replaces it
+	source, err = NewSource(store, "trakt", Token{AccessToken: "new"}, r.refresh, discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token, _ := source.AccessToken(context.Background()); token != "new" {
+		t.Errorf("AccessToken = %q, want the new configured token", token)
+	}
+}
+
+func TestSourceAccessToken(t *testing.T) {
+	tests := []struct {
+		name    string
+		token   Token
+		refresh error // Error of the refresh func, if set
+		noFunc  bool  // No refresh func at all
+		want    string
+		err     string
+		calls   int
+	}{
+		{
+			name:  "valid",
+			token: Token{AccessToken: "a", RefreshToken: "r", Expiry: time.Now().Add(48 * time.Hour)},
+			want:  "a",
+		},
+		{
+			name:  "unknown expiry",
+			token: Token{AccessToken: "a", RefreshToken: "r"},
+			want:  "a",
+		},
+		{
+			name:  "expires soon",
+			token: Token{AccessToken: "a", RefreshToken: "r", Expiry: time.Now().Add(10 * time.Minute)},
+			want:  "refreshed-r",
+			calls: 1,
+		},
+		{
+			name:    "refresh fails before expiry",
+			token:   Token{AccessToken: "a", RefreshToken: "r", Expiry: time.Now().Add(10 * time.Minute)},
+			refresh: errors.New("unavailable"),
+			want:    "a",
+			calls:   1,
+		},
+		{
+			name:    "refresh fails after expiry",
+			token:   Token{AccessToken: "a", RefreshToken: "r", Expiry: time.Now().Add(-time.Minute)},
+			refresh: errors.New("unavailable"),
+			err:     "failed to refresh access token: unavailable",
+			calls:   1,
+		},
+		{
+			name:   "expired without refresh",
+			token:  Token{AccessToken: "a", Expiry: time.Now().Add(-time.Minute)},
+			noFunc: true,
+			err:    "cannot be refreshed, authorize again",
+		},
+		{
+			name: "no token",
+			err:  ErrNoToken.Error(),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &refresher{err: tt.refresh}
+			refresh := RefreshFunc(r.refresh)
+			if tt.noFunc {
+				refresh = nil
+			}
+			store := NewMemoryStore()
+			source, err := NewSource(store, "mal", tt.token, refresh, discard)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := source.AccessToken(context.Background())
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Errorf("error = %v, want %q", err, tt.err)
+				}
+			} else if err != nil || got != tt.want {
+				t.Errorf("AccessToken = %q, %v, want %q", got, err, tt.want)
+			}
+			if r.calls != tt.calls {
+				t.Errorf("refreshed %d times, want %d", r.calls, tt.calls)
+			}
+
+			// A refreshed token is stored and keeps the refresh token
+			if tt.calls > 0 && tt.refresh == nil {
+				stored, _ := store.Get("mal")
+				if stored.AccessToken != tt.want || stored.RefreshToken != tt.token.RefreshToken {
+					t.Errorf("stored %+v", stored)
+				}
+			}
+		})
+	}
+}
+
+func TestSourceRefreshRejected(t *testing.T) {
+	r := &refresher{}
+	source, err := NewSource(NewMemoryStore(), "anilist", Token{AccessToken: "a", RefreshToken: "r"}, r.refresh, discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	token, err := source.Refresh(context.Background(), "a")
+	if err != nil || token != "refreshed-r" {
+		t.Fatalf("Refresh = %q, %v", token, err)
+	}
+
+	// A request that was rejected with the old token gets the new one
+	// without another refresh
+	token, err = source.Refresh(context.Background(), "a")
+	if err != nil || token != "refreshed-r" || r.calls != 1 {
+		t.Errorf("Refresh = %q, %v after %d calls, want the new token after 1", token, err, r.calls)
+	}
+}
+
+func TestJWTExpiry(t *testing.T) {
+	// {"exp":1700000000}
+	token := "eyJhbGciOiJIUzI1NiJ9.eyJleHAiOjE3MDAwMDAwMDB9.signature"
+	if got := jwtExpiry(token); !got.Equal(time.Unix(1700000000, 0)) {
+		t.Errorf("jwtExpiry = %v", got)
+	}
+	if got := jwtExpiry("opaque-token"); !got.IsZero() {
+		t.Errorf("jwtExpiry of an opaque token = %v, want zero", got)
+	}
+}
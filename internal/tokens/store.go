@@ -0,0 +1,181 @@
+package tokens
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Token is an OAuth token of a plugin instance
+type Token struct {
+	AccessToken  string    `json:"accessToken"`
+	RefreshToken string    `json:"refreshToken,omitempty"`
+	Expiry       time.Time `json:"expiry,omitzero"` // Zero if unknown
+
+	// Origin identifies the configured access token this token was
+	// refreshed from, so a new token in the config replaces stored ones
+	Origin string `json:"origin,omitempty"`
+}
+
+// Store keeps the tokens of plugin instances
+type Store interface {
+	// Get returns the token of a plugin instance, if any
+	Get(pluginID string) (Token, bool)
+
+	// Put replaces the token of a plugin instance
+	Put(pluginID string, token Token) error
+}
+
+// MemoryStore keeps tokens until the process exits
+type MemoryStore struct {
+	mu     sync.Mutex
+	tokens map[string]Token
+}
+
+// NewMemoryStore creates an empty memory store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{tokens: make(map[string]Token)}
+}
+
+func (s *MemoryStore) Get(pluginID string) (Token, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	token, ok := s.tokens[pluginID]
+	return token, ok
+}
+
+func (s *MemoryStore) Put(pluginID string, token Token) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tokens[pluginID] = token
+	return nil
+}
+
+// FileStore keeps tokens in a JSON file, keyed by plugin instance ID. The
+// file is only readable by its owner since it holds credentials. It is read
+// again whenever it changes, so tokens saved by another process are seen.
+type FileStore struct {
+	mu     sync.Mutex
+	path   string
+	tokens map[string]Token
+	info   fs.FileInfo // Of the file tokens were read from, nil if none
+}
+
+// OpenFile opens the token file at path, which is created on the first Put
+// if it does not exist
+func OpenFile(path string) (*FileStore, error) {
+	s := &FileStore{path: path}
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// load reads the token file if it changed since it was last read. s.mu must
+// be held.
+func (s *FileStore) load() error {
+	info, err := os.Stat(s.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		s.tokens, s.info = make(map[string]Token), nil
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if s.info != nil && info.ModTime().Equal(s.info.ModTime()) && info.Size() == s.info.Size() {
+		return nil
+	}
+
+	tokens, err := s.read()
+	if err != nil {
+		return err
+	}
+	s.tokens, s.info = tokens, info
+	return nil
+}
+
+// read reads the token file, which is empty if it doesn't exist
+func (s *FileStore) read() (map[string]Token, error) {
+	tokens := make(map[string]Token)
+	data, err := os.ReadFile(s.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return tokens, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &tokens); err != nil {
+		return nil, fmt.Errorf("failed to parse token file %s: %w", s.path, err)
+	}
+	return tokens, nil
+}
+
+// Get returns the token of a plugin instance. If the file can't be read
+// again, the tokens it held before are used.
+func (s *FileStore) Get(pluginID string) (Token, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_ = s.load()
+	token, ok := s.tokens[pluginID]
+	return token, ok
+}
+
+func (s *FileStore) Put(pluginID string, token Token) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// The file is read again so tokens saved by another process, such as
+	// "modcal auth" next to a running server, are kept
+	tokens, err := s.read()
+	if err != nil {
+		return err
+	}
+	tokens[pluginID] = token
+	if err := s.write(tokens); err != nil {
+		return err
+	}
+	s.tokens = tokens
+	s.info, _ = os.Stat(s.path)
+	return nil
+}
+
+// write replaces the token file through a temporary file, so a crash never
+// leaves a partial file behind. s.mu must be held.
+func (s *FileStore) write(tokens map[string]Token) error {
+	data, err := json.MarshalIndent(tokens, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	dir := filepath.Dir(s.path)
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return fmt.Errorf("failed to create token directory: %w", err)
+	}
+	tmp, err := os.CreateTemp(dir, ".tokens-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), s.path)
+}
+
+// NewStore creates a file store at path, or a memory store if path is empty
+func NewStore(path string) (Store, error) {
+	if path == "" {
+		return NewMemoryStore(), nil
+	}
+	return OpenFile(path)
+}
@@ -0,0 +1,76 @@
+package tokens
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+)
+
+func TestFileStoreSeesOtherProcesses(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tokens.json")
+	server, err := OpenFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := server.Get("mal"); ok {
+		t.Fatal("Get on a new store returned a token")
+	}
+
+	// "modcal auth" opens the same file in another process
+	auth, err := OpenFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := auth.Put("mal", Token{AccessToken: "first"}); err != nil {
+		t.Fatal(err)
+	}
+	if token, ok := server.Get("mal"); !ok || token.AccessToken != "first" {
+		t.Errorf("Get = %+v, %v, want the token stored by auth", token, ok)
+	}
+
+	// A Put from the server keeps tokens the other process stored
+	if err := auth.Put("trakt", Token{AccessToken: "trakt"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := server.Put("mal", Token{AccessToken: "second"}); err != nil {
+		t.Fatal(err)
+	}
+	if token, ok := auth.Get("trakt"); !ok || token.AccessToken != "trakt" {
+		t.Errorf("trakt = %+v, %v after Put of mal", token, ok)
+	}
+	if token, _ := auth.Get("mal"); token.AccessToken != "second" {
+		t.Errorf("mal = %+v, want second", token)
+	}
+}
+
+func TestSourceSeesAuthorization(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tokens.json")
+
+	server, err := OpenFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	running, err := NewSource(server, "mal", Token{}, nil, discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := running.AccessToken(context.Background()); err != ErrNoToken {
+		t.Fatalf("AccessToken before authorization: %v, want ErrNoToken", err)
+	}
+
+	auth, err := OpenFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	authorizing, err := NewSource(auth, "mal", Token{}, nil, discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := authorizing.Set(Token{AccessToken: "authorized"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if token, err := running.AccessToken(context.Background()); err != nil || token != "authorized" {
+		t.Errorf("AccessToken = %q, %v, want authorized", token, err)
+	}
+}
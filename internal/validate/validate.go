@@ -0,0 +1,524 @@
+// Package validate checks config files and reports every problem found with
+// its position in the file
+package validate
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/jacobsee/modcal/internal/config"
+	"github.com/jacobsee/modcal/internal/logging"
+	"github.com/jacobsee/modcal/internal/plugin"
+	"gopkg.in/yaml.v3"
+)
+
+// Severity tells whether an issue keeps modcal from starting
+type Severity int
+
+const (
+	Warning Severity = iota
+	Error
+)
+
+func (s Severity) String() string {
+	if s == Error {
+		return "error"
+	}
+	return "warning"
+}
+
+// Issue is a problem found in a config file
+type Issue struct {
+	File     string
+	Line     int    // 0 if unknown
+	Column   int    // 0 if unknown
+	Path     string // Location in the config, e.g. "plugins[1].config"
+	Severity Severity
+	Message  string
+}
+
+// String formats the issue like compiler errors, e.g.
+// "config.yaml:12:7: error: plugins[1]: unknown plugin type "trak""
+func (i Issue) String() string {
+	var b strings.Builder
+	b.WriteString(i.File)
+	if i.Line > 0 {
+		fmt.Fprintf(&b, ":%d", i.Line)
+		if i.Column > 0 {
+			fmt.Fprintf(&b, ":%d", i.Column)
+		}
+	}
+	fmt.Fprintf(&b, ": %s: ", i.Severity)
+	if i.Path != "" {
+		b.WriteString(i.Path + ": ")
+	}
+	b.WriteString(i.Message)
+	return b.String()
+}
+
+// HasErrors reports whether any of the issues is an error
+func HasErrors(issues []Issue) bool {
+	for _, issue := range issues {
+		if issue.Severity == Error {
+			return true
+		}
+	}
+	return false
+}
+
+// Checks are checks of the host that need more than the config file, such
+// as building a plugin instance. They run after the config passed the
+// checks of this package, and the errors they return are reported at the
+// plugin or calendar.
+type Checks struct {
+	Plugin   func(cfg config.PluginConfig) error
+	Calendar func(cfg config.CalendarConfig) error
+}
+
+// File loads and checks a config file. It returns the config with defaults
+// applied, or nil if the file could not be parsed, and the issues found;
+// the error is only set if the file could not be read.
+func File(path string, registry *plugin.Registry, checks Checks) (*config.Config, []Issue, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	cfg, issues := Data(path, data, registry, checks)
+	return cfg, issues, nil
+}
+
+// Data checks the contents of a config file, see File
+func Data(file string, data []byte, registry *plugin.Registry, checks Checks) (*config.Config, []Issue) {
+	v := &validator{file: file, registry: registry, checks: checks}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		v.yamlError(err)
+		return nil, v.issues
+	}
+
+	var cfg config.Config
+	root := &doc
+	if doc.Kind == yaml.DocumentNode && len(doc.Content) > 0 {
+		root = resolve(doc.Content[0])
+		if root.Kind != yaml.MappingNode && !isNull(root) {
+			v.add(Error, root, "", "config must be a mapping of options")
+			return nil, v.issues
+		}
+		v.checkKeys(root, reflect.TypeOf(cfg), "")
+		if err := doc.Decode(&cfg); err != nil {
+			v.yamlError(err)
+		}
+	}
+	cfg.ApplyDefaults()
+
+	v.checkGeneral(&cfg, root)
+	used := v.checkCalendars(&cfg, child(root, "calendars"))
+	v.checkPlugins(&cfg, child(root, "plugins"), used)
+
+	sort.SliceStable(v.issues, func(i, j int) bool {
+		a, b := v.issues[i], v.issues[j]
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		return a.Column < b.Column
+	})
+	return &cfg, v.issues
+}
+
+type validator struct {
+	file     string
+	registry *plugin.Registry
+	checks   Checks
+	issues   []Issue
+}
+
+func (v *validator) add(severity Severity, node *yaml.Node, path, format string, args ...any) {
+	issue := Issue{
+		File:     v.file,
+		Path:     path,
+		Severity: severity,
+		Message:  fmt.Sprintf(format, args...),
+	}
+	if node != nil {
+		issue.Line, issue.Column = node.Line, node.Column
+	}
+	v.issues = append(v.issues, issue)
+}
+
+// yamlLine matches the position yaml.v3 puts in its error messages
+var yamlLine = regexp.MustCompile(`^(?:yaml: )?line (\d+): (.*)$`)
+
+// yamlError adds syntax and type errors, one per message
+func (v *validator) yamlError(err error) {
+	messages := []string{err.Error()}
+	var typeErr *yaml.TypeError
+	if errors.As(err, &typeErr) {
+		messages = typeErr.Errors
+	}
+
+	for _, msg := range messages {
+		issue := Issue{File: v.file, Severity: Error, Message: strings.TrimPrefix(msg, "yaml: ")}
+		if m := yamlLine.FindStringSubmatch(msg); m != nil {
+			issue.Line, _ = strconv.Atoi(m[1])
+			issue.Message = m[2]
+		}
+		v.issues = append(v.issues, issue)
+	}
+}
+
+// checkKeys reports keys of node that no field of t is decoded from
+func (v *validator) checkKeys(node *yaml.Node, t reflect.Type, path string) {
+	node = resolve(node)
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	switch t.Kind() {
+	case reflect.Slice:
+		if node.Kind != yaml.SequenceNode {
+			return
+		}
+		for i, item := range node.Content {
+			v.checkKeys(item, t.Elem(), fmt.Sprintf("%s[%d]", path, i))
+		}
+
+	case reflect.Struct:
+		// Other kinds are type errors, or shorthands such as "spoilerFree: true"
+		if node.Kind != yaml.MappingNode {
+			return
+		}
+		fields := make(map[string]reflect.Type)
+		var names []string
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			name, _, _ := strings.Cut(field.Tag.Get("yaml"), ",")
+			if name == "" || name == "-" || !field.IsExported() {
+				continue
+			}
+			fields[name] = field.Type
+			names = append(names, name)
+		}
+
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+			if key.Value == "<<" {
+				continue
+			}
+			fieldPath := join(path, key.Value)
+			fieldType, ok := fields[key.Value]
+			if !ok {
+				v.add(Error, key, path, "unknown option %q%s", key.Value, suggestion(key.Value, names))
+				continue
+			}
+			v.checkKeys(value, fieldType, fieldPath)
+		}
+	}
+}
+
+// checkGeneral checks the options outside of plugins and calendars
+func (v *validator) checkGeneral(cfg *config.Config, root *yaml.Node) {
+	server := child(root, "server")
+	if cfg.Server.Port < 1 || cfg.Server.Port > 65535 {
+		v.add(Error, field(server, "port"), "server.port", "port must be between 1 and 65535")
+	}
+
+	auth := child(root, "auth")
+	switch cfg.Auth.Method {
+	case "none":
+	case "apikey":
+		if cfg.Auth.APIKey == "" {
+			v.add(Error, field(auth, "apiKey"), "auth.apiKey", "apiKey is required with method apikey")
+		}
+	default:
+		v.add(Error, field(auth, "method"), "auth.method", "unknown method %q, use none or apikey", cfg.Auth.Method)
+	}
+
+	scheduler := child(root, "scheduler")
+	if cfg.Scheduler.Interval < 0 {
+		v.add(Error, field(scheduler, "interval"), "scheduler.interval", "interval must not be negative")
+	}
+	if *cfg.Scheduler.Jitter < 0 {
+		v.add(Error, field(scheduler, "jitter"), "scheduler.jitter", "jitter must not be negative")
+	}
+
+	storage := child(root, "storage")
+	switch cfg.Storage.Type {
+	case "memory":
+	case "file":
+		if cfg.Storage.Path == "" {
+			v.add(Error, field(storage, "path"), "storage.path", "path is required with type file")
+		}
+	default:
+		v.add(Error, field(storage, "type"), "storage.type", "unknown type %q, use memory or file", cfg.Storage.Type)
+	}
+
+	loggingNode := child(root, "logging")
+	if _, err := logging.New(io.Discard, cfg.Logging.Level, "text"); err != nil {
+		v.add(Error, field(loggingNode, "level"), "logging.level", "%v, use debug, info, warn or error", err)
+	}
+	if _, err := logging.New(io.Discard, "info", cfg.Logging.Format); err != nil {
+		v.add(Error, field(loggingNode, "format"), "logging.format", "%v, use text or json", err)
+	}
+}
+
+// checkPlugins checks the plugin instances. used holds the IDs calendars
+// refer to.
+func (v *validator) checkPlugins(cfg *config.Config, plugins *yaml.Node, used map[string]bool) {
+	types := v.registry.List()
+	sort.Strings(types)
+	seen := make(map[string]*yaml.Node)
+
+	for i, pluginCfg := range cfg.Plugins {
+		node := item(plugins, i)
+		path := fmt.Sprintf("plugins[%d]", i)
+		errorsBefore := v.errorCount()
+
+		switch first, dup := seen[pluginCfg.ID]; {
+		case pluginCfg.ID == "":
+			v.add(Error, field(node, "id"), path, "id is required")
+		case dup:
+			v.add(Error, field(node, "id"), path, "duplicate plugin id %q, first used on line %d", pluginCfg.ID, first.Line)
+		default:
+			seen[pluginCfg.ID] = field(node, "id")
+			if !used[pluginCfg.ID] {
+				v.add(Warning, field(node, "id"), path, "plugin %q is not part of any calendar", pluginCfg.ID)
+			}
+		}
+
+		if pluginCfg.Type == "" {
+			v.add(Error, field(node, "type"), path, "type is required, one of %s", strings.Join(types, ", "))
+			continue
+		}
+		p, err := v.registry.Get(pluginCfg.Type)
+		if err != nil {
+			hint := suggestion(pluginCfg.Type, types)
+			if hint == "" {
+				hint = ", one of " + strings.Join(types, ", ")
+			}
+			v.add(Error, field(node, "type"), path, "unknown plugin type %q%s", pluginCfg.Type, hint)
+			continue
+		}
+
+		v.checkOptions(pluginCfg, p.Schema(), node, path+".config")
+
+		if v.checks.Plugin != nil && v.errorCount() == errorsBefore {
+			if err := v.checks.Plugin(pluginCfg); err != nil {
+				v.add(Error, node, path, "%v", err)
+			}
+		}
+	}
+}
+
+// checkOptions checks a plugin's config against the schema of its type
+func (v *validator) checkOptions(pluginCfg config.PluginConfig, schema *plugin.Schema, node *yaml.Node, path string) {
+	configNode := child(node, "config")
+	names := make([]string, 0, len(schema.Options))
+	for _, opt := range schema.Options {
+		names = append(names, opt.Name)
+		if opt.Required && pluginCfg.Config[opt.Name] == nil {
+			at := configNode
+			if at == nil {
+				at = node
+			}
+			v.add(Error, at, path, "option %q is required by plugin type %s", opt.Name, pluginCfg.Type)
+		}
+	}
+
+	keys := make([]string, 0, len(pluginCfg.Config))
+	for key := range pluginCfg.Config {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		opt, ok := schema.Option(key)
+		if !ok {
+			v.add(Error, keyNode(configNode, key), path, "unknown option %q of plugin type %s%s", key, pluginCfg.Type, suggestion(key, names))
+			continue
+		}
+		if value := pluginCfg.Config[key]; value != nil {
+			if err := opt.Check(value); err != nil {
+				v.add(Error, field(configNode, key), join(path, key), "%v", err)
+			}
+		}
+	}
+}
+
+// checkCalendars checks the calendars and returns the plugin IDs they
+// refer to
+func (v *validator) checkCalendars(cfg *config.Config, calendars *yaml.Node) map[string]bool {
+	ids := make(map[string]bool)
+	for _, pluginCfg := range cfg.Plugins {
+		ids[pluginCfg.ID] = true
+	}
+	var known []string
+	for id := range ids {
+		known = append(known, id)
+	}
+
+	used := make(map[string]bool)
+	seen := make(map[string]*yaml.Node)
+	for i, calCfg := range cfg.Calendars {
+		node := item(calendars, i)
+		path := fmt.Sprintf("calendars[%d]", i)
+		errorsBefore := v.errorCount()
+
+		switch first, dup := seen[calCfg.Name]; {
+		case calCfg.Name == "":
+			v.add(Error, field(node, "name"), path, "name is required")
+		case dup:
+			v.add(Error, field(node, "name"), path, "duplicate calendar name %q, first used on line %d", calCfg.Name, first.Line)
+		default:
+			seen[calCfg.Name] = field(node, "name")
+		}
+
+		pluginsNode := child(node, "plugins")
+		if len(calCfg.PluginIDs) == 0 {
+			v.add(Warning, field(node, "plugins"), path, "calendar has no plugins")
+		}
+		inCalendar := make(map[string]bool)
+		for j, id := range calCfg.PluginIDs {
+			at := item(pluginsNode, j)
+			if at == nil {
+				at = pluginsNode
+			}
+			used[id] = true
+			switch {
+			case !ids[id]:
+				v.add(Error, at, fmt.Sprintf("%s.plugins[%d]", path, j), "unknown plugin id %q%s", id, suggestion(id, known))
+			case inCalendar[id]:
+				v.add(Warning, at, fmt.Sprintf("%s.plugins[%d]", path, j), "plugin %q is listed more than once", id)
+			}
+			inCalendar[id] = true
+		}
+
+		if v.checks.Calendar != nil && v.errorCount() == errorsBefore {
+			if err := v.checks.Calendar(calCfg); err != nil {
+				v.add(Error, node, path, "%v", err)
+			}
+		}
+	}
+	return used
+}
+
+func (v *validator) errorCount() int {
+	n := 0
+	for _, issue := range v.issues {
+		if issue.Severity == Error {
+			n++
+		}
+	}
+	return n
+}
+
+// resolve follows aliases to the node they refer to
+func resolve(node *yaml.Node) *yaml.Node {
+	for node != nil && node.Kind == yaml.AliasNode {
+		node = node.Alias
+	}
+	return node
+}
+
+func isNull(node *yaml.Node) bool {
+	return node.Kind == yaml.ScalarNode && node.Tag == "!!null"
+}
+
+// keyNode returns the key node of key in a mapping, or the mapping if it has
+// no such key
+func keyNode(mapping *yaml.Node, key string) *yaml.Node {
+	mapping = resolve(mapping)
+	if mapping == nil || mapping.Kind != yaml.MappingNode {
+		return mapping
+	}
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			return mapping.Content[i]
+		}
+	}
+	return mapping
+}
+
+// child returns the value of key in a mapping, or nil
+func child(mapping *yaml.Node, key string) *yaml.Node {
+	mapping = resolve(mapping)
+	if mapping == nil || mapping.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			return resolve(mapping.Content[i+1])
+		}
+	}
+	return nil
+}
+
+// field returns the value of key in a mapping to report issues at, or the
+// mapping if the key is missing
+func field(mapping *yaml.Node, key string) *yaml.Node {
+	if value := child(mapping, key); value != nil {
+		return value
+	}
+	return resolve(mapping)
+}
+
+// item returns the i-th item of a sequence, or nil
+func item(seq *yaml.Node, i int) *yaml.Node {
+	seq = resolve(seq)
+	if seq == nil || seq.Kind != yaml.SequenceNode || i >= len(seq.Content) {
+		return nil
+	}
+	return resolve(seq.Content[i])
+}
+
+func join(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// suggestion returns ", did you mean ...?" for the known name closest to a
+// misspelled one, or "" if none is close
+func suggestion(name string, known []string) string {
+	best, bestDist := "", 3
+	for _, k := range known {
+		if strings.EqualFold(k, name) {
+			return fmt.Sprintf(", did you mean %q?", k)
+		}
+		if d := distance(strings.ToLower(name), strings.ToLower(k)); d < bestDist {
+			best, bestDist = k, d
+		}
+	}
+	if best == "" || bestDist >= len(name) {
+		return ""
+	}
+	return fmt.Sprintf(", did you mean %q?", best)
+}
+
+// distance returns the Levenshtein distance of two strings
+func distance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
@@ -0,0 +1,224 @@
+package validate
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/jacobsee/modcal/internal/config"
+	"github.com/jacobsee/modcal/internal/plugin"
+	"github.com/jacobsee/modcal/plugins/ics"
+)
+
+func newRegistry(t *testing.T) *plugin.Registry {
+	t.Helper()
+	registry := plugin.NewRegistry()
+	if err := registry.Register(ics.New()); err != nil {
+		t.Fatal(err)
+	}
+	return registry
+}
+
+func issueStrings(issues []Issue) []string {
+	var out []string
+	for _, issue := range issues {
+		out = append(out, issue.String())
+	}
+	return out
+}
+
+func TestData(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want []string
+	}{
+		{
+			name: "valid",
+			data: `
+plugins:
+  - id: holidays
+    type: ics
+    config:
+      url: https://example.com/holidays.ics
+calendars:
+  - name: main
+    plugins: [holidays]
+`,
+		},
+		{
+			name: "unknown keys",
+			data: `
+server:
+  prot: 8080
+plugins:
+  - id: holidays
+    type: ics
+    config:
+      url: https://example.com/holidays.ics
+      daysBak: 7
+calendars:
+  - name: main
+    plugins: [holidays]
+`,
+			want: []string{
+				`config.yaml:3:3: error: server: unknown option "prot", did you mean "port"?`,
+				`config.yaml:9:7: error: plugins[0].config: unknown option "daysBak" of plugin type ics, did you mean "daysBack"?`,
+			},
+		},
+		{
+			name: "duplicates and unknown plugin IDs",
+			data: `
+plugins:
+  - id: holidays
+    type: ics
+    config:
+      url: https://example.com/holidays.ics
+  - id: holidays
+    type: ics
+    config:
+      url: https://example.com/other.ics
+calendars:
+  - name: main
+    plugins: [holidays, holiday]
+  - name: main
+    plugins: [holidays]
+`,
+			want: []string{
+				`config.yaml:7:9: error: plugins[1]: duplicate plugin id "holidays", first used on line 3`,
+				`config.yaml:13:25: error: calendars[0].plugins[1]: unknown plugin id "holiday", did you mean "holidays"?`,
+				`config.yaml:14:11: error: calendars[1]: duplicate calendar name "main", first used on line 12`,
+			},
+		},
+		{
+			name: "plugin options",
+			data: `
+plugins:
+  - id: holidays
+    type: icss
+  - id: local
+    type: ics
+    config:
+      daysBack: soon
+calendars:
+  - name: main
+    plugins: [holidays, local]
+`,
+			want: []string{
+				`config.yaml:4:11: error: plugins[0]: unknown plugin type "icss", did you mean "ics"?`,
+				`config.yaml:8:7: error: plugins[1].config: option "url" is required by plugin type ics`,
+				`config.yaml:8:17: error: plugins[1].config.daysBack: ` + wantCheck(t, "daysBack", "soon"),
+			},
+		},
+		{
+			name: "general options",
+			data: `
+server:
+  port: 70000
+auth:
+  method: apikey
+storage:
+  type: disk
+`,
+			want: []string{
+				`config.yaml:3:9: error: server.port: port must be between 1 and 65535`,
+				`config.yaml:5:3: error: auth.apiKey: apiKey is required with method apikey`,
+				`config.yaml:7:9: error: storage.type: unknown type "disk", use memory or file`,
+			},
+		},
+		{
+			name: "type errors",
+			data: `
+server:
+  port: http
+`,
+			want: []string{
+				"config.yaml:3: error: cannot unmarshal !!str `http` into int",
+			},
+		},
+		{
+			name: "syntax errors",
+			data: "server:\n  host: localhost\n  port: 80: 80\n",
+			want: []string{
+				"config.yaml:3: error: mapping values are not allowed in this context",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, issues := Data("config.yaml", []byte(tt.data), newRegistry(t), Checks{})
+			got := strings.Join(issueStrings(issues), "\n")
+			if want := strings.Join(tt.want, "\n"); got != want {
+				t.Errorf("issues:\n%s\nwant:\n%s", got, want)
+			}
+		})
+	}
+}
+
+// wantCheck returns the message of an ics option rejecting a value
+func wantCheck(t *testing.T, name string, value any) string {
+	t.Helper()
+	opt, ok := ics.New().Schema().Option(name)
+	if !ok {
+		t.Fatalf("ics has no option %q", name)
+	}
+	err := opt.Check(value)
+	if err == nil {
+		t.Fatalf("option %q accepts %v", name, value)
+	}
+	return err.Error()
+}
+
+func TestDataWarnings(t *testing.T) {
+	data := `
+plugins:
+  - id: holidays
+    type: ics
+    config:
+      url: https://example.com/holidays.ics
+calendars:
+  - name: main
+    plugins: []
+`
+	_, issues := Data("config.yaml", []byte(data), newRegistry(t), Checks{})
+	if HasErrors(issues) {
+		t.Errorf("HasErrors = true for warnings: %v", issueStrings(issues))
+	}
+	want := []string{
+		`config.yaml:3:9: warning: plugins[0]: plugin "holidays" is not part of any calendar`,
+		`config.yaml:9:14: warning: calendars[0]: calendar has no plugins`,
+	}
+	if got := strings.Join(issueStrings(issues), "\n"); got != strings.Join(want, "\n") {
+		t.Errorf("issues:\n%s\nwant:\n%s", got, strings.Join(want, "\n"))
+	}
+}
+
+func TestDataChecks(t *testing.T) {
+	data := `
+plugins:
+  - id: holidays
+    type: ics
+    config:
+      url: https://example.com/holidays.ics
+calendars:
+  - name: main
+    plugins: [holidays]
+`
+	checks := Checks{
+		Plugin: func(cfg config.PluginConfig) error {
+			return errors.New("cannot create " + cfg.ID)
+		},
+		Calendar: func(cfg config.CalendarConfig) error {
+			return errors.New("cannot build " + cfg.Name)
+		},
+	}
+	_, issues := Data("config.yaml", []byte(data), newRegistry(t), checks)
+	want := []string{
+		`config.yaml:3:5: error: plugins[0]: cannot create holidays`,
+		`config.yaml:8:5: error: calendars[0]: cannot build main`,
+	}
+	if got := strings.Join(issueStrings(issues), "\n"); got != strings.Join(want, "\n") {
+		t.Errorf("issues:\n%s\nwant:\n%s", got, strings.Join(want, "\n"))
+	}
+}
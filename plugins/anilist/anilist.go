@@ -7,30 +7,36 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/jacobsee/modcal/internal/models"
 	"github.com/jacobsee/modcal/internal/plugin"
+	"github.com/jacobsee/modcal/internal/tokens"
 )
 
 const (
 	graphqlURL = "https://graphql.anilist.co"
+
+	defaultSummaryTemplate     = "{{.show}} - Episode {{.episode}}"
+	defaultDescriptionTemplate = "{{if .totalEpisodes}}Episode {{.episode}} of {{.totalEpisodes}}{{end}}"
 )
 
 // AniListPlugin fetches episode release info from AniList
 type AniListPlugin struct {
-	accessToken string
-	daysBack    int
-	daysForward int
-	client      *http.Client
+	clientID     string
+	clientSecret string
+	tokens       *tokens.Source
+	daysBack     int
+	daysForward  int
+	templates    *plugin.Templates
+	client       *http.Client
 }
 
 // New creates a new AniList plugin instance
 func New() *AniListPlugin {
 	return &AniListPlugin{
-		client: &http.Client{
-			Timeout: 30 * time.Second,
-		},
+		client: plugin.NewHTTPClient("anilist"),
 	}
 }
 
@@ -38,36 +44,60 @@ func (p *AniListPlugin) Name() string {
 	return "anilist"
 }
 
-func (p *AniListPlugin) Create(config map[string]interface{}) (plugin.Plugin, error) {
-	instance := &AniListPlugin{
-		client: &http.Client{
-			Timeout: 30 * time.Second,
-		},
-	}
+// Config is the config of an AniList plugin instance
+type Config struct {
+	ClientID     string `option:"clientId" description:"AniList API client ID, needed to authorize through modcal"`
+	ClientSecret string `option:"clientSecret,secret" description:"AniList API client secret, needed to authorize through modcal"`
+	AccessToken  string `option:"accessToken,secret" description:"OAuth access token, unless authorized through modcal"`
+	DaysBack     int    `option:"daysBack" default:"7" description:"Days to look back"`
+	DaysForward  int    `option:"daysForward" default:"14" description:"Days to look forward"`
+	plugin.TemplateConfig
+}
+
+var schema = plugin.MustSchema(Config{})
+
+func (p *AniListPlugin) Schema() *plugin.Schema {
+	return schema
+}
 
-	accessToken, ok := config["accessToken"].(string)
-	if !ok || accessToken == "" {
-		return nil, fmt.Errorf("accessToken is required")
+func (p *AniListPlugin) Create(config map[string]interface{}, env plugin.Env) (plugin.Plugin, error) {
+	var cfg Config
+	if err := schema.Decode(config, &cfg); err != nil {
+		return nil, err
 	}
-	instance.accessToken = accessToken
 
-	// Optional: days to look back (default: 7)
-	if daysBack, ok := config["daysBack"].(int); ok {
-		instance.daysBack = daysBack
-	} else {
-		instance.daysBack = 7
+	instance := &AniListPlugin{
+		clientID:     cfg.ClientID,
+		clientSecret: cfg.ClientSecret,
+		daysBack:     cfg.DaysBack,
+		daysForward:  cfg.DaysForward,
+		client:       plugin.NewHTTPClient("anilist"),
+	}
+
+	// The access token may be left out if the instance is authorized through
+	// modcal, which keeps it in the token store. AniList tokens last a year
+	// and cannot be refreshed.
+	configured := tokens.Token{AccessToken: cfg.AccessToken}
+	source, err := tokens.NewSource(env.Tokens, env.ID, configured, nil, env.Logger)
+	if err != nil {
+		return nil, err
 	}
+	instance.tokens = source
 
-	// Optional: days to look forward (default: 14)
-	if daysForward, ok := config["daysForward"].(int); ok {
-		instance.daysForward = daysForward
-	} else {
-		instance.daysForward = 14
+	templates, err := plugin.NewTemplates(cfg.TemplateConfig, env.Logger, defaultSummaryTemplate, defaultDescriptionTemplate)
+	if err != nil {
+		return nil, err
 	}
+	instance.templates = templates
 
 	return instance, nil
 }
 
+// Render sets the event's summary and description from its fields
+func (p *AniListPlugin) Render(event *models.Event) {
+	p.templates.Render(event)
+}
+
 func (p *AniListPlugin) FetchEvents(ctx context.Context) ([]models.Event, error) {
 	userID, err := p.getAuthenticatedUserID(ctx)
 	if err != nil {
@@ -207,6 +237,7 @@ func (p *AniListPlugin) getAiringSchedules(ctx context.Context, mediaIDs []int,
 				mediaId
 				media {
 					id
+					idMal
 					title {
 						romaji
 						english
@@ -246,6 +277,12 @@ func (p *AniListPlugin) getAiringSchedules(ctx context.Context, mediaIDs []int,
 }
 
 func (p *AniListPlugin) executeQuery(ctx context.Context, query string, variables map[string]interface{}, result interface{}) error {
+	return plugin.WithToken(ctx, p.tokens, func(accessToken string) error {
+		return p.post(ctx, query, variables, accessToken, result)
+	})
+}
+
+func (p *AniListPlugin) post(ctx context.Context, query string, variables map[string]interface{}, accessToken string, result interface{}) error {
 	requestBody := map[string]interface{}{
 		"query": query,
 	}
@@ -265,9 +302,7 @@ func (p *AniListPlugin) executeQuery(ctx context.Context, query string, variable
 
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("Accept", "application/json")
-	if p.accessToken != "" {
-		req.Header.Set("Authorization", "Bearer "+p.accessToken)
-	}
+	req.Header.Set("Authorization", "Bearer "+accessToken)
 
 	resp, err := p.client.Do(req)
 	if err != nil {
@@ -281,7 +316,7 @@ func (p *AniListPlugin) executeQuery(ctx context.Context, query string, variable
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("API returned status %d: %s", resp.StatusCode, string(body))
+		return &plugin.HTTPError{StatusCode: resp.StatusCode, Body: string(body)}
 	}
 
 	return json.Unmarshal(body, result)
@@ -298,8 +333,6 @@ func (p *AniListPlugin) convertToEvents(schedules []AiringSchedule) []models.Eve
 			title = schedule.Media.Title.English
 		}
 
-		summary := fmt.Sprintf("%s - Episode %d", title, schedule.Episode)
-
 		// Calculate end time based on duration
 		endTime := airTime
 		if schedule.Media.Duration > 0 {
@@ -308,27 +341,39 @@ func (p *AniListPlugin) convertToEvents(schedules []AiringSchedule) []models.Eve
 			endTime = airTime.Add(24 * time.Minute) // Default anime episode length, not precise
 		}
 
-		uid := fmt.Sprintf("anilist-%d-ep%d-%d",
+		// The UID identifies the episode, not its air time, so a delayed
+		// episode is an update to the same event rather than a new one
+		uid := fmt.Sprintf("anilist-%d-ep%d",
 			schedule.MediaID,
 			schedule.Episode,
-			schedule.AiringAt,
 		)
 
-		description := ""
-		if schedule.Media.Episodes > 0 {
-			description = fmt.Sprintf("Episode %d of %d", schedule.Episode, schedule.Media.Episodes)
-		}
-
 		event := models.Event{
-			UID:         uid,
-			Summary:     summary,
-			Description: description,
-			StartTime:   airTime,
-			EndTime:     endTime,
-			AllDay:      false,
-			URL:         schedule.Media.SiteURL,
-			Categories:  []string{"anime", "anilist"},
+			UID:        uid,
+			StartTime:  airTime,
+			EndTime:    endTime,
+			AllDay:     false,
+			URL:        schedule.Media.SiteURL,
+			Categories: []string{"anime", "anilist"},
+			Show:       title,
+			ExternalIDs: map[string]string{
+				"anilist": strconv.Itoa(schedule.MediaID),
+			},
+		}
+		if schedule.Media.IDMal > 0 {
+			event.ExternalIDs["mal"] = strconv.Itoa(schedule.Media.IDMal)
+		}
+		event.Fields = map[string]interface{}{
+			"show":          title,
+			"titleRomaji":   schedule.Media.Title.Romaji,
+			"titleEnglish":  schedule.Media.Title.English,
+			"titleNative":   schedule.Media.Title.Native,
+			"episode":       schedule.Episode,
+			"totalEpisodes": schedule.Media.Episodes,
+			"runtime":       schedule.Media.Duration,
+			"airTime":       airTime,
 		}
+		p.templates.Render(&event)
 
 		events = append(events, event)
 	}
@@ -348,6 +393,7 @@ type AiringSchedule struct {
 // Media represents anime information
 type Media struct {
 	ID       int       `json:"id"`
+	IDMal    int       `json:"idMal"`
 	Title    Title     `json:"title"`
 	Episodes int       `json:"episodes"`
 	Duration int       `json:"duration"`
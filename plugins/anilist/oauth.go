@@ -0,0 +1,96 @@
+package anilist
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/jacobsee/modcal/internal/plugin"
+	"github.com/jacobsee/modcal/internal/tokens"
+)
+
+const (
+	authURL  = "https://anilist.co/api/v2/oauth/authorize"
+	tokenURL = "https://anilist.co/api/v2/oauth/token"
+
+	// AniList shows the code on this page for the user to copy
+	pinRedirectURI = "https://anilist.co/api/v2/oauth/pin"
+)
+
+// tokenResponse is the response of the token endpoint
+type tokenResponse struct {
+	TokenType   string `json:"token_type"`
+	ExpiresIn   int    `json:"expires_in"`
+	AccessToken string `json:"access_token"`
+}
+
+// StartAuth starts the PIN flow: AniList shows a code after authorizing,
+// which the user pastes back
+func (p *AniListPlugin) StartAuth(ctx context.Context, redirectURL string) (*plugin.AuthFlow, error) {
+	if p.clientID == "" || p.clientSecret == "" {
+		return nil, fmt.Errorf("clientId and clientSecret are required to authorize")
+	}
+
+	params := url.Values{}
+	params.Set("client_id", p.clientID)
+	params.Set("redirect_uri", pinRedirectURI)
+	params.Set("response_type", "code")
+
+	return &plugin.AuthFlow{
+		URL: authURL + "?" + params.Encode(),
+		Finish: func(ctx context.Context, code string) error {
+			token, err := p.exchangeCode(ctx, code)
+			if err != nil {
+				return fmt.Errorf("failed to exchange code: %w", err)
+			}
+			return p.tokens.Set(token)
+		},
+	}, nil
+}
+
+// exchangeCode exchanges an authorization code for an access token
+func (p *AniListPlugin) exchangeCode(ctx context.Context, code string) (tokens.Token, error) {
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("client_id", p.clientID)
+	form.Set("client_secret", p.clientSecret)
+	form.Set("redirect_uri", pinRedirectURI)
+	form.Set("code", code)
+
+	req, err := http.NewRequestWithContext(ctx, "POST", tokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return tokens.Token{}, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return tokens.Token{}, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return tokens.Token{}, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return tokens.Token{}, &plugin.HTTPError{StatusCode: resp.StatusCode, Body: string(body)}
+	}
+
+	var token tokenResponse
+	if err := json.Unmarshal(body, &token); err != nil {
+		return tokens.Token{}, fmt.Errorf("failed to decode token: %w", err)
+	}
+
+	result := tokens.Token{AccessToken: token.AccessToken}
+	if token.ExpiresIn > 0 {
+		result.Expiry = time.Now().Add(time.Duration(token.ExpiresIn) * time.Second)
+	}
+	return result, nil
+}
@@ -23,14 +23,23 @@ func (p *ExamplePlugin) Name() string {
 	return "example"
 }
 
-func (p *ExamplePlugin) Create(config map[string]interface{}) (plugin.Plugin, error) {
-	instance := &ExamplePlugin{}
-	if msg, ok := config["message"].(string); ok {
-		instance.message = msg
-	} else {
-		instance.message = "Default example event"
+// Config is the config of an example plugin instance
+type Config struct {
+	Message string `option:"message" default:"Default example event" description:"Summary of the first example event"`
+}
+
+var schema = plugin.MustSchema(Config{})
+
+func (p *ExamplePlugin) Schema() *plugin.Schema {
+	return schema
+}
+
+func (p *ExamplePlugin) Create(config map[string]interface{}, env plugin.Env) (plugin.Plugin, error) {
+	var cfg Config
+	if err := schema.Decode(config, &cfg); err != nil {
+		return nil, err
 	}
-	return instance, nil
+	return &ExamplePlugin{message: cfg.Message}, nil
 }
 
 func (p *ExamplePlugin) FetchEvents(ctx context.Context) ([]models.Event, error) {
@@ -0,0 +1,163 @@
+package ics
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/jacobsee/modcal/internal/ical"
+	"github.com/jacobsee/modcal/internal/models"
+	"github.com/jacobsee/modcal/internal/plugin"
+)
+
+// ICSPlugin imports events from an iCalendar feed or file
+type ICSPlugin struct {
+	url         string
+	daysBack    int
+	daysForward int
+	categories  []string
+	client      *http.Client
+	logger      *slog.Logger
+}
+
+// New creates a new ICS plugin instance
+func New() *ICSPlugin {
+	return &ICSPlugin{
+		client: plugin.NewHTTPClient("ics"),
+	}
+}
+
+func (p *ICSPlugin) Name() string {
+	return "ics"
+}
+
+// Config is the config of an ICS plugin instance
+type Config struct {
+	URL         string   `option:"url,required" description:"URL (https://, http:// or webcal://) or file path of the calendar"`
+	DaysBack    int      `option:"daysBack" default:"30" description:"Days to look back"`
+	DaysForward int      `option:"daysForward" default:"90" description:"Days to look forward"`
+	Categories  []string `option:"categories" default:"ics" description:"Categories of every event"`
+}
+
+var schema = plugin.MustSchema(Config{})
+
+func (p *ICSPlugin) Schema() *plugin.Schema {
+	return schema
+}
+
+func (p *ICSPlugin) Create(config map[string]interface{}, env plugin.Env) (plugin.Plugin, error) {
+	var cfg Config
+	if err := schema.Decode(config, &cfg); err != nil {
+		return nil, err
+	}
+
+	// webcal:// is just a hint for calendar apps, the feed itself is served over HTTP(S)
+	url := cfg.URL
+	if strings.HasPrefix(url, "webcal://") {
+		url = "https://" + strings.TrimPrefix(url, "webcal://")
+	}
+
+	instance := &ICSPlugin{
+		url:         url,
+		daysBack:    cfg.DaysBack,
+		daysForward: cfg.DaysForward,
+		client:      plugin.NewHTTPClient("ics"),
+		logger:      env.Logger,
+	}
+	for _, c := range cfg.Categories {
+		if c != "" {
+			instance.categories = append(instance.categories, c)
+		}
+	}
+
+	return instance, nil
+}
+
+func (p *ICSPlugin) FetchEvents(ctx context.Context) ([]models.Event, error) {
+	body, err := p.open(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+
+	now := time.Now()
+	windowStart := now.AddDate(0, 0, -p.daysBack)
+	windowEnd := now.AddDate(0, 0, p.daysForward)
+
+	cal, err := ical.ParseWith(body, ical.ParseOptions{
+		From: windowStart,
+		To:   windowEnd,
+		Skip: func(err error) {
+			p.logger.Warn("Skipped invalid event", "error", err)
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse calendar: %w", err)
+	}
+
+	var events []models.Event
+	for _, event := range cal.Events {
+		end := event.EndTime
+		if end.IsZero() {
+			end = event.StartTime
+		}
+		if end.Before(windowStart) || event.StartTime.After(windowEnd) {
+			continue
+		}
+
+		if event.UID == "" {
+			event.UID = p.syntheticUID(event)
+		}
+		event.Categories = append(event.Categories, p.categories...)
+
+		events = append(events, event)
+	}
+
+	return events, nil
+}
+
+// open returns the raw feed, either from a local file or over HTTP
+func (p *ICSPlugin) open(ctx context.Context) (io.ReadCloser, error) {
+	if path, ok := strings.CutPrefix(p.url, "file://"); ok || !strings.Contains(p.url, "://") {
+		if !ok {
+			path = p.url
+		}
+		file, err := os.Open(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open calendar file: %w", err)
+		}
+		return file, nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", p.url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Accept", "text/calendar")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch calendar: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		return nil, fmt.Errorf("calendar feed: %w", &plugin.HTTPError{StatusCode: resp.StatusCode, Body: string(body)})
+	}
+
+	return resp.Body, nil
+}
+
+// syntheticUID builds a stable UID for feeds that omit one
+func (p *ICSPlugin) syntheticUID(event models.Event) string {
+	hash := sha1.Sum([]byte(fmt.Sprintf("%s|%s|%d", p.url, event.Summary, event.StartTime.Unix())))
+	return "ics-" + hex.EncodeToString(hash[:8])
+}
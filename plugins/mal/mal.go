@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"log/slog"
 	"net/http"
 	"strconv"
 	"strings"
@@ -12,28 +13,37 @@ import (
 
 	"github.com/jacobsee/modcal/internal/models"
 	"github.com/jacobsee/modcal/internal/plugin"
+	"github.com/jacobsee/modcal/internal/tokens"
 )
 
 const (
 	baseURL = "https://api.myanimelist.net/v2"
+
+	// broadcastZone is the zone MAL broadcast times are given in
+	broadcastZone = "Asia/Tokyo"
+
+	defaultSummaryTemplate     = "{{.show}} - New Episode"
+	defaultDescriptionTemplate = "New episode airs{{if .totalEpisodes}} (Total: {{.totalEpisodes}} episodes){{end}}"
 )
 
 // MALPlugin fetches anime from MyAnimeList
 type MALPlugin struct {
 	clientID     string
-	accessToken  string
-	refreshToken string
+	clientSecret string
+	redirectURI  string
+	tokens       *tokens.Source
 	weeksBack    int
 	weeksForward int
+	recurring    bool
+	templates    *plugin.Templates
 	client       *http.Client
+	logger       *slog.Logger
 }
 
 // New creates a new MAL plugin instance
 func New() *MALPlugin {
 	return &MALPlugin{
-		client: &http.Client{
-			Timeout: 30 * time.Second,
-		},
+		client: plugin.NewHTTPClient("mal"),
 	}
 }
 
@@ -41,48 +51,65 @@ func (p *MALPlugin) Name() string {
 	return "mal"
 }
 
-func (p *MALPlugin) Create(config map[string]interface{}) (plugin.Plugin, error) {
-	instance := &MALPlugin{
-		client: &http.Client{
-			Timeout: 30 * time.Second,
-		},
-	}
+// Config is the config of a MyAnimeList plugin instance
+type Config struct {
+	ClientID     string `option:"clientId,required" description:"MyAnimeList API client ID"`
+	ClientSecret string `option:"clientSecret,secret" description:"MyAnimeList API client secret, if the app has one"`
+	RedirectURI  string `option:"redirectUri" description:"Redirect URL of the app, used when authorizing"`
+	AccessToken  string `option:"accessToken,secret" description:"OAuth access token, unless authorized through modcal"`
+	RefreshToken string `option:"refreshToken,secret" description:"OAuth refresh token"`
+	WeeksBack    int    `option:"weeksBack" default:"1" description:"Weeks to look back"`
+	WeeksForward int    `option:"weeksForward" default:"2" description:"Weeks to look forward"`
+	Recurring    bool   `option:"recurring" description:"Publish one recurring series per anime instead of weekly events"`
+	plugin.TemplateConfig
+}
 
-	clientID, ok := config["clientId"].(string)
-	if !ok || clientID == "" {
-		return nil, fmt.Errorf("clientId is required")
-	}
-	instance.clientID = clientID
+var schema = plugin.MustSchema(Config{})
+
+func (p *MALPlugin) Schema() *plugin.Schema {
+	return schema
+}
 
-	accessToken, ok := config["accessToken"].(string)
-	if !ok || accessToken == "" {
-		return nil, fmt.Errorf("accessToken is required")
+func (p *MALPlugin) Create(config map[string]interface{}, env plugin.Env) (plugin.Plugin, error) {
+	var cfg Config
+	if err := schema.Decode(config, &cfg); err != nil {
+		return nil, err
 	}
-	instance.accessToken = accessToken
 
-	// Optional refresh token
-	// TODO: implement token refresh logic
-	if refreshToken, ok := config["refreshToken"].(string); ok {
-		instance.refreshToken = refreshToken
+	instance := &MALPlugin{
+		clientID:     cfg.ClientID,
+		clientSecret: cfg.ClientSecret,
+		redirectURI:  cfg.RedirectURI,
+		weeksBack:    cfg.WeeksBack,
+		weeksForward: cfg.WeeksForward,
+		recurring:    cfg.Recurring,
+		client:       plugin.NewHTTPClient("mal"),
+		logger:       env.Logger,
 	}
 
-	// Optional: weeks to look back (default: 1)
-	if weeksBack, ok := config["weeksBack"].(int); ok {
-		instance.weeksBack = weeksBack
-	} else {
-		instance.weeksBack = 1
+	// The access token may be left out if the instance is authorized through
+	// modcal, which keeps it in the token store
+	configured := tokens.Token{AccessToken: cfg.AccessToken, RefreshToken: cfg.RefreshToken}
+	source, err := tokens.NewSource(env.Tokens, env.ID, configured, instance.refreshToken, env.Logger)
+	if err != nil {
+		return nil, err
 	}
+	instance.tokens = source
 
-	// Optional: weeks to look forward (default: 2)
-	if weeksForward, ok := config["weeksForward"].(int); ok {
-		instance.weeksForward = weeksForward
-	} else {
-		instance.weeksForward = 2
+	templates, err := plugin.NewTemplates(cfg.TemplateConfig, env.Logger, defaultSummaryTemplate, defaultDescriptionTemplate)
+	if err != nil {
+		return nil, err
 	}
+	instance.templates = templates
 
 	return instance, nil
 }
 
+// Render sets the event's summary and description from its fields
+func (p *MALPlugin) Render(event *models.Event) {
+	p.templates.Render(event)
+}
+
 func (p *MALPlugin) FetchEvents(ctx context.Context) ([]models.Event, error) {
 	watching, err := p.getWatchingList(ctx)
 	if err != nil {
@@ -97,7 +124,17 @@ func (p *MALPlugin) FetchEvents(ctx context.Context) ([]models.Event, error) {
 	var events []models.Event
 	for _, anime := range watching {
 		if anime.Node.Broadcast.DayOfWeek == "" {
-			continue // Skip anime without broadcast info
+			p.logger.Debug("Skipping anime without broadcast info", "show", anime.Node.Title)
+			continue
+		}
+
+		if p.recurring {
+			if event, ok := p.generateSeriesForAnime(anime.Node); ok {
+				events = append(events, event)
+				continue
+			}
+			// Without a start the series has no stable DTSTART
+			p.logger.Debug("Publishing weekly events for anime without a start date", "show", anime.Node.Title)
 		}
 
 		animeEvents := p.generateEventsForAnime(anime.Node)
@@ -108,7 +145,7 @@ func (p *MALPlugin) FetchEvents(ctx context.Context) ([]models.Event, error) {
 }
 
 func (p *MALPlugin) getWatchingList(ctx context.Context) ([]AnimeListItem, error) {
-	url := fmt.Sprintf("%s/users/@me/animelist?status=watching&fields=broadcast,num_episodes&limit=100", baseURL)
+	url := fmt.Sprintf("%s/users/@me/animelist?status=watching&fields=broadcast,num_episodes,start_date,start_season&limit=100", baseURL)
 
 	var allItems []AnimeListItem
 	for url != "" {
@@ -119,7 +156,10 @@ func (p *MALPlugin) getWatchingList(ctx context.Context) ([]AnimeListItem, error
 			} `json:"paging"`
 		}
 
-		if err := p.makeRequest(ctx, url, &response); err != nil {
+		err := plugin.WithToken(ctx, p.tokens, func(accessToken string) error {
+			return p.makeRequest(ctx, url, accessToken, &response)
+		})
+		if err != nil {
 			return nil, err
 		}
 
@@ -130,14 +170,14 @@ func (p *MALPlugin) getWatchingList(ctx context.Context) ([]AnimeListItem, error
 	return allItems, nil
 }
 
-func (p *MALPlugin) makeRequest(ctx context.Context, url string, result interface{}) error {
+func (p *MALPlugin) makeRequest(ctx context.Context, url, accessToken string, result interface{}) error {
 	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
 		return err
 	}
 
 	req.Header.Set("X-MAL-CLIENT-ID", p.clientID)
-	req.Header.Set("Authorization", "Bearer "+p.accessToken)
+	req.Header.Set("Authorization", "Bearer "+accessToken)
 
 	resp, err := p.client.Do(req)
 	if err != nil {
@@ -151,7 +191,7 @@ func (p *MALPlugin) makeRequest(ctx context.Context, url string, result interfac
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("API returned status %d: %s", resp.StatusCode, string(body))
+		return &plugin.HTTPError{StatusCode: resp.StatusCode, Body: string(body)}
 	}
 
 	return json.Unmarshal(body, result)
@@ -167,17 +207,18 @@ func (p *MALPlugin) generateEventsForAnime(anime Anime) []models.Event {
 	}
 
 	broadcastTime := p.parseTime(anime.Broadcast.StartTime)
+	jst := p.broadcastLocation()
 
-	// Calculate date range
-	now := time.Now()
+	// Calculate date range; broadcast days are Japanese weekdays
+	now := time.Now().In(jst)
 	startDate := now.AddDate(0, 0, -7*p.weeksBack)
 	endDate := now.AddDate(0, 0, 7*p.weeksForward)
 
 	// Find all occurrences of this weekday within the range
 	current := p.nextWeekday(startDate, weekday)
 	for current.Before(endDate) || current.Equal(endDate) {
-		// Set the broadcast time (in JST)
-		jst := time.FixedZone("JST", 9*60*60)
+		// Set the broadcast time (in JST). Events keep their zone so output
+		// doesn't depend on the server's TZ setting.
 		airTime := time.Date(
 			current.Year(),
 			current.Month(),
@@ -188,35 +229,28 @@ func (p *MALPlugin) generateEventsForAnime(anime Anime) []models.Event {
 			jst,
 		)
 
-		// Convert to local time
-		airTime = airTime.In(time.Local)
-
 		// Create event
 		uid := fmt.Sprintf("mal-%d-%s",
 			anime.ID,
 			airTime.Format("2006-01-02"),
 		)
 
-		summary := fmt.Sprintf("%s - New Episode", anime.Title)
-
-		description := "New episode airs"
-		if anime.NumEpisodes > 0 {
-			description = fmt.Sprintf("New episode airs (Total: %d episodes)", anime.NumEpisodes)
-		}
-
 		// Default 24 minute duration
 		endTime := airTime.Add(24 * time.Minute)
 
 		event := models.Event{
 			UID:         uid,
-			Summary:     summary,
-			Description: description,
 			StartTime:   airTime,
 			EndTime:     endTime,
 			AllDay:      false,
+			TimeZone:    jst.String(),
 			URL:         fmt.Sprintf("https://myanimelist.net/anime/%d", anime.ID),
 			Categories:  []string{"anime", "mal"},
+			Show:        anime.Title,
+			ExternalIDs: map[string]string{"mal": strconv.Itoa(anime.ID)},
+			Fields:      p.fields(anime, airTime),
 		}
+		p.templates.Render(&event)
 
 		events = append(events, event)
 
@@ -227,6 +261,82 @@ func (p *MALPlugin) generateEventsForAnime(anime Anime) []models.Event {
 	return events
 }
 
+// generateSeriesForAnime creates a single weekly recurring event for the
+// anime's broadcast slot, starting with the first broadcast on or after the
+// anime's start date and ending after its episodes if their number is known
+func (p *MALPlugin) generateSeriesForAnime(anime Anime) (models.Event, bool) {
+	weekday := p.parseDayOfWeek(anime.Broadcast.DayOfWeek)
+	if weekday == -1 {
+		return models.Event{}, false
+	}
+
+	broadcastTime := p.parseTime(anime.Broadcast.StartTime)
+	jst := p.broadcastLocation()
+	start, ok := p.seriesStart(anime, jst)
+	if !ok {
+		return models.Event{}, false
+	}
+	first := p.nextWeekday(start, weekday)
+
+	rule := "FREQ=WEEKLY"
+	if anime.NumEpisodes > 0 {
+		rule += fmt.Sprintf(";COUNT=%d", anime.NumEpisodes)
+	}
+
+	// The series stays in JST so every occurrence lands on the same
+	// broadcast slot regardless of DST changes in the local timezone
+	airTime := time.Date(
+		first.Year(),
+		first.Month(),
+		first.Day(),
+		broadcastTime.Hour(),
+		broadcastTime.Minute(),
+		0, 0,
+		jst,
+	)
+
+	event := models.Event{
+		UID:         fmt.Sprintf("mal-%d", anime.ID),
+		StartTime:   airTime,
+		EndTime:     airTime.Add(24 * time.Minute),
+		AllDay:      false,
+		TimeZone:    jst.String(),
+		URL:         fmt.Sprintf("https://myanimelist.net/anime/%d", anime.ID),
+		Categories:  []string{"anime", "mal"},
+		Show:        anime.Title,
+		ExternalIDs: map[string]string{"mal": strconv.Itoa(anime.ID)},
+		Fields:      p.fields(anime, airTime),
+		Recurrence: &models.Recurrence{
+			Rule: rule,
+		},
+	}
+	p.templates.Render(&event)
+
+	return event, true
+}
+
+// fields returns the template data of an anime's broadcast
+func (p *MALPlugin) fields(anime Anime, airTime time.Time) map[string]interface{} {
+	return map[string]interface{}{
+		"show":          anime.Title,
+		"totalEpisodes": anime.NumEpisodes,
+		"broadcastDay":  anime.Broadcast.DayOfWeek,
+		"broadcastTime": anime.Broadcast.StartTime,
+		"runtime":       24,
+		"airTime":       airTime,
+	}
+}
+
+// broadcastLocation returns the JST zone, falling back to a fixed offset if
+// the tz database is unavailable (Japan has no DST, so they are equivalent)
+func (p *MALPlugin) broadcastLocation() *time.Location {
+	loc, err := models.LoadZone(broadcastZone)
+	if err != nil {
+		return time.FixedZone("JST", 9*60*60)
+	}
+	return loc
+}
+
 func (p *MALPlugin) parseDayOfWeek(day string) time.Weekday {
 	day = strings.ToLower(strings.TrimSpace(day))
 	switch day {
@@ -262,6 +372,29 @@ func (p *MALPlugin) parseTime(timeStr string) time.Time {
 	return time.Date(0, 1, 1, hour, minute, 0, 0, time.UTC)
 }
 
+// seasonMonths are the first months of the seasons MAL groups anime by
+var seasonMonths = map[string]time.Month{
+	"winter": time.January,
+	"spring": time.April,
+	"summer": time.July,
+	"fall":   time.October,
+}
+
+// seriesStart returns the day a series starts from: the anime's start date,
+// or else the start of the season it started in. Both come from MAL, so
+// DTSTART doesn't move between fetches or restarts.
+func (p *MALPlugin) seriesStart(anime Anime, loc *time.Location) (time.Time, bool) {
+	for _, layout := range []string{"2006-01-02", "2006-01", "2006"} {
+		if start, err := time.ParseInLocation(layout, anime.StartDate, loc); err == nil {
+			return start, true
+		}
+	}
+	if month, ok := seasonMonths[anime.StartSeason.Season]; ok && anime.StartSeason.Year > 0 {
+		return time.Date(anime.StartSeason.Year, month, 1, 0, 0, 0, 0, loc), true
+	}
+	return time.Time{}, false
+}
+
 func (p *MALPlugin) nextWeekday(from time.Time, weekday time.Weekday) time.Time {
 	days := int(weekday) - int(from.Weekday())
 	if days < 0 {
@@ -280,9 +413,17 @@ type Anime struct {
 	ID          int       `json:"id"`
 	Title       string    `json:"title"`
 	NumEpisodes int       `json:"num_episodes"`
+	StartDate   string    `json:"start_date"` // "2006-01-02", "2006-01" or "2006"
+	StartSeason Season    `json:"start_season"`
 	Broadcast   Broadcast `json:"broadcast"`
 }
 
+// Season is a season of a year, e.g. "spring" 2024
+type Season struct {
+	Year   int    `json:"year"`
+	Season string `json:"season"`
+}
+
 // Broadcast represents broadcast information
 type Broadcast struct {
 	DayOfWeek string `json:"day_of_the_week"`
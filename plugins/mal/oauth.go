@@ -0,0 +1,117 @@
+package mal
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/jacobsee/modcal/internal/plugin"
+	"github.com/jacobsee/modcal/internal/tokens"
+)
+
+const (
+	authURL  = "https://myanimelist.net/v1/oauth2/authorize"
+	tokenURL = "https://myanimelist.net/v1/oauth2/token"
+)
+
+// tokenResponse is the response of the token endpoint
+type tokenResponse struct {
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int    `json:"expires_in"`
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+}
+
+func (r *tokenResponse) token() tokens.Token {
+	token := tokens.Token{AccessToken: r.AccessToken, RefreshToken: r.RefreshToken}
+	if r.ExpiresIn > 0 {
+		token.Expiry = time.Now().Add(time.Duration(r.ExpiresIn) * time.Second)
+	}
+	return token
+}
+
+// StartAuth starts the authorization code flow with PKCE. MAL redirects
+// back to the redirectUri from the config if set, which must match the one
+// registered for the app, or to redirectURL otherwise.
+func (p *MALPlugin) StartAuth(ctx context.Context, redirectURL string) (*plugin.AuthFlow, error) {
+	if p.redirectURI != "" {
+		redirectURL = p.redirectURI
+	}
+
+	// MAL only supports the "plain" method, so the challenge is the verifier
+	verifier := plugin.RandomString()
+	state := plugin.RandomString()
+
+	params := url.Values{}
+	params.Set("response_type", "code")
+	params.Set("client_id", p.clientID)
+	params.Set("redirect_uri", redirectURL)
+	params.Set("code_challenge", verifier)
+	params.Set("code_challenge_method", "plain")
+	params.Set("state", state)
+
+	return &plugin.AuthFlow{
+		URL:   authURL + "?" + params.Encode(),
+		State: state,
+		Finish: func(ctx context.Context, code string) error {
+			form := url.Values{}
+			form.Set("grant_type", "authorization_code")
+			form.Set("redirect_uri", redirectURL)
+			form.Set("code", code)
+			form.Set("code_verifier", verifier)
+
+			token, err := p.requestToken(ctx, form)
+			if err != nil {
+				return fmt.Errorf("failed to exchange code: %w", err)
+			}
+			return p.tokens.Set(token)
+		},
+	}, nil
+}
+
+// refreshToken exchanges a refresh token for a new access token
+func (p *MALPlugin) refreshToken(ctx context.Context, refreshToken string) (tokens.Token, error) {
+	form := url.Values{}
+	form.Set("grant_type", "refresh_token")
+	form.Set("refresh_token", refreshToken)
+	return p.requestToken(ctx, form)
+}
+
+// requestToken posts a token request with the app's credentials
+func (p *MALPlugin) requestToken(ctx context.Context, form url.Values) (tokens.Token, error) {
+	form.Set("client_id", p.clientID)
+	if p.clientSecret != "" {
+		form.Set("client_secret", p.clientSecret)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", tokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return tokens.Token{}, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return tokens.Token{}, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return tokens.Token{}, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return tokens.Token{}, &plugin.HTTPError{StatusCode: resp.StatusCode, Body: string(body)}
+	}
+
+	var token tokenResponse
+	if err := json.Unmarshal(body, &token); err != nil {
+		return tokens.Token{}, fmt.Errorf("failed to decode token: %w", err)
+	}
+	return token.token(), nil
+}
@@ -0,0 +1,174 @@
+package trakt
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/jacobsee/modcal/internal/plugin"
+	"github.com/jacobsee/modcal/internal/tokens"
+)
+
+// Tokens issued through the device flow are bound to this redirect URI
+const deviceRedirectURI = "urn:ietf:wg:oauth:2.0:oob"
+
+// tokenResponse is the response of the token endpoint
+type tokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int    `json:"expires_in"`
+	RefreshToken string `json:"refresh_token"`
+	Scope        string `json:"scope"`
+	CreatedAt    int64  `json:"created_at"`
+}
+
+func (r *tokenResponse) token() tokens.Token {
+	token := tokens.Token{AccessToken: r.AccessToken, RefreshToken: r.RefreshToken}
+	if r.ExpiresIn > 0 {
+		created := time.Now()
+		if r.CreatedAt > 0 {
+			created = time.Unix(r.CreatedAt, 0)
+		}
+		token.Expiry = created.Add(time.Duration(r.ExpiresIn) * time.Second)
+	}
+	return token
+}
+
+// deviceCodeResponse is the response when starting the device flow
+type deviceCodeResponse struct {
+	DeviceCode      string `json:"device_code"`
+	UserCode        string `json:"user_code"`
+	VerificationURL string `json:"verification_url"`
+	ExpiresIn       int    `json:"expires_in"`
+	Interval        int    `json:"interval"`
+}
+
+// StartAuth starts the device flow: the user enters a code on the Trakt
+// website while the token endpoint is polled
+func (p *TraktPlugin) StartAuth(ctx context.Context, redirectURL string) (*plugin.AuthFlow, error) {
+	if p.clientSecret == "" {
+		return nil, fmt.Errorf("clientSecret is required to authorize")
+	}
+
+	var device deviceCodeResponse
+	if err := p.post(ctx, "/oauth/device/code", map[string]string{"client_id": p.clientID}, &device); err != nil {
+		return nil, fmt.Errorf("failed to get device code: %w", err)
+	}
+
+	expires := time.Now().Add(time.Duration(device.ExpiresIn) * time.Second)
+	return &plugin.AuthFlow{
+		URL:      device.VerificationURL,
+		UserCode: device.UserCode,
+		Expires:  expires,
+		Finish: func(ctx context.Context, _ string) error {
+			ctx, cancel := context.WithDeadline(ctx, expires)
+			defer cancel()
+
+			token, err := p.pollDeviceToken(ctx, &device)
+			if err != nil {
+				return err
+			}
+			return p.tokens.Set(token.token())
+		},
+	}, nil
+}
+
+// pollDeviceToken polls until the user approved or denied the device code
+func (p *TraktPlugin) pollDeviceToken(ctx context.Context, device *deviceCodeResponse) (*tokenResponse, error) {
+	reqBody := map[string]string{
+		"code":          device.DeviceCode,
+		"client_id":     p.clientID,
+		"client_secret": p.clientSecret,
+	}
+
+	interval := time.Duration(max(device.Interval, 1)) * time.Second
+	for {
+		select {
+		case <-ctx.Done():
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				return nil, fmt.Errorf("device code expired")
+			}
+			return nil, ctx.Err()
+		case <-time.After(interval):
+		}
+
+		var token tokenResponse
+		err := p.post(ctx, "/oauth/device/token", reqBody, &token)
+		var httpErr *plugin.HTTPError
+		if !errors.As(err, &httpErr) {
+			if err != nil {
+				return nil, err
+			}
+			return &token, nil
+		}
+
+		switch httpErr.StatusCode {
+		case http.StatusBadRequest:
+			// Pending, the user hasn't entered the code yet
+		case http.StatusTooManyRequests:
+			interval += time.Second
+		case http.StatusNotFound, http.StatusGone:
+			return nil, fmt.Errorf("device code expired")
+		case http.StatusConflict:
+			return nil, fmt.Errorf("device code was already used")
+		case http.StatusTeapot:
+			return nil, fmt.Errorf("access denied by user")
+		default:
+			return nil, err
+		}
+	}
+}
+
+// post sends a JSON request to the Trakt API and decodes the response
+func (p *TraktPlugin) post(ctx context.Context, path string, reqBody interface{}, result interface{}) error {
+	jsonData, err := json.Marshal(reqBody)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", baseURL+path, bytes.NewReader(jsonData))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return &plugin.HTTPError{StatusCode: resp.StatusCode, Body: string(body)}
+	}
+
+	if err := json.Unmarshal(body, result); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
+
+// refreshToken exchanges a refresh token for a new access token
+func (p *TraktPlugin) refreshToken(ctx context.Context, refreshToken string) (tokens.Token, error) {
+	var token tokenResponse
+	err := p.post(ctx, "/oauth/token", map[string]string{
+		"refresh_token": refreshToken,
+		"client_id":     p.clientID,
+		"client_secret": p.clientSecret,
+		"redirect_uri":  deviceRedirectURI,
+		"grant_type":    "refresh_token",
+	}, &token)
+	if err != nil {
+		return tokens.Token{}, err
+	}
+	return token.token(), nil
+}
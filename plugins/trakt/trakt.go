@@ -5,33 +5,40 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"log/slog"
 	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/jacobsee/modcal/internal/models"
 	"github.com/jacobsee/modcal/internal/plugin"
+	"github.com/jacobsee/modcal/internal/tokens"
 )
 
 const (
 	baseURL    = "https://api.trakt.tv"
 	apiVersion = "2"
+
+	defaultSummaryTemplate     = `{{.show}} - S{{printf "%02d" .season}}E{{printf "%02d" .episode}}{{with .episodeTitle}}: {{.}}{{end}}`
+	defaultDescriptionTemplate = "{{.overview}}{{if and .overview .network}}\n\n{{end}}{{with .network}}Network: {{.}}{{end}}"
 )
 
 // TraktPlugin fetches TV show episodes from Trakt
 type TraktPlugin struct {
-	clientID    string
-	accessToken string
-	daysBack    int
-	daysForward int
-	client      *http.Client
+	clientID     string
+	clientSecret string
+	tokens       *tokens.Source
+	daysBack     int
+	daysForward  int
+	templates    *plugin.Templates
+	client       *http.Client
+	logger       *slog.Logger
 }
 
 // New creates a new Trakt plugin instance
 func New() *TraktPlugin {
 	return &TraktPlugin{
-		client: &http.Client{
-			Timeout: 30 * time.Second,
-		},
+		client: plugin.NewHTTPClient("trakt"),
 	}
 }
 
@@ -39,42 +46,67 @@ func (p *TraktPlugin) Name() string {
 	return "trakt"
 }
 
-func (p *TraktPlugin) Create(config map[string]interface{}) (plugin.Plugin, error) {
-	instance := &TraktPlugin{
-		client: &http.Client{
-			Timeout: 30 * time.Second,
-		},
+// Config is the config of a Trakt plugin instance
+type Config struct {
+	ClientID     string `option:"clientId,required" description:"Trakt API client ID"`
+	ClientSecret string `option:"clientSecret,secret" description:"Trakt API client secret, needed to authorize and refresh tokens"`
+	AccessToken  string `option:"accessToken,secret" description:"OAuth access token, unless authorized through modcal"`
+	RefreshToken string `option:"refreshToken,secret" description:"OAuth refresh token"`
+	DaysBack     int    `option:"daysBack" default:"7" description:"Days to look back"`
+	DaysForward  int    `option:"daysForward" default:"14" description:"Days to look forward"`
+	plugin.TemplateConfig
+}
+
+var schema = plugin.MustSchema(Config{})
+
+func (p *TraktPlugin) Schema() *plugin.Schema {
+	return schema
+}
+
+func (p *TraktPlugin) Create(config map[string]interface{}, env plugin.Env) (plugin.Plugin, error) {
+	var cfg Config
+	if err := schema.Decode(config, &cfg); err != nil {
+		return nil, err
 	}
 
-	clientID, ok := config["clientId"].(string)
-	if !ok || clientID == "" {
-		return nil, fmt.Errorf("clientId is required")
+	instance := &TraktPlugin{
+		clientID:    cfg.ClientID,
+		daysBack:    cfg.DaysBack,
+		daysForward: cfg.DaysForward,
+		client:      plugin.NewHTTPClient("trakt"),
+		logger:      env.Logger,
 	}
-	instance.clientID = clientID
 
-	accessToken, ok := config["accessToken"].(string)
-	if !ok || accessToken == "" {
-		return nil, fmt.Errorf("accessToken is required")
+	// The access token may be left out if the instance is authorized through
+	// modcal, which keeps it in the token store. Refreshing and authorizing
+	// need the client secret.
+	configured := tokens.Token{AccessToken: cfg.AccessToken, RefreshToken: cfg.RefreshToken}
+	var refresh tokens.RefreshFunc
+	if cfg.ClientSecret != "" {
+		instance.clientSecret = cfg.ClientSecret
+		refresh = instance.refreshToken
 	}
-	instance.accessToken = accessToken
 
-	// Optional: days to look back (default: 7)
-	if daysBack, ok := config["daysBack"].(int); ok {
-		instance.daysBack = daysBack
-	} else {
-		instance.daysBack = 7
+	source, err := tokens.NewSource(env.Tokens, env.ID, configured, refresh, env.Logger)
+	if err != nil {
+		return nil, err
 	}
+	instance.tokens = source
 
-	// Optional: days to look forward (default: 14)
-	if daysForward, ok := config["daysForward"].(int); ok {
-		instance.daysForward = daysForward
-	} else {
-		instance.daysForward = 14
+	templates, err := plugin.NewTemplates(cfg.TemplateConfig, env.Logger, defaultSummaryTemplate, defaultDescriptionTemplate)
+	if err != nil {
+		return nil, err
 	}
+	instance.templates = templates
 
 	return instance, nil
 }
 
+// Render sets the event's summary and description from its fields
+func (p *TraktPlugin) Render(event *models.Event) {
+	p.templates.Render(event)
+}
+
 func (p *TraktPlugin) FetchEvents(ctx context.Context) ([]models.Event, error) {
 	startDate := time.Now().AddDate(0, 0, -p.daysBack)
 	totalDays := p.daysBack + p.daysForward
@@ -83,33 +115,43 @@ func (p *TraktPlugin) FetchEvents(ctx context.Context) ([]models.Event, error) {
 
 	url := fmt.Sprintf("%s/calendars/my/shows/%s/%d", baseURL, startDateStr, totalDays)
 
+	var calendarItems []CalendarItem
+	err := plugin.WithToken(ctx, p.tokens, func(accessToken string) error {
+		return p.fetchCalendar(ctx, url, accessToken, &calendarItems)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return p.convertToEvents(calendarItems), nil
+}
+
+func (p *TraktPlugin) fetchCalendar(ctx context.Context, url, accessToken string, result *[]CalendarItem) error {
 	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
+		return fmt.Errorf("failed to create request: %w", err)
 	}
 
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("trakt-api-version", apiVersion)
 	req.Header.Set("trakt-api-key", p.clientID)
-	req.Header.Set("Authorization", "Bearer "+p.accessToken)
+	req.Header.Set("Authorization", "Bearer "+accessToken)
 
 	resp, err := p.client.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("failed to fetch calendar: %w", err)
+		return fmt.Errorf("failed to fetch calendar: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("trakt API returned status %d: %s", resp.StatusCode, string(body))
+		return &plugin.HTTPError{StatusCode: resp.StatusCode, Body: string(body)}
 	}
 
-	var calendarItems []CalendarItem
-	if err := json.NewDecoder(resp.Body).Decode(&calendarItems); err != nil {
-		return nil, fmt.Errorf("failed to decode response: %w", err)
+	if err := json.NewDecoder(resp.Body).Decode(result); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
 	}
-
-	return p.convertToEvents(calendarItems), nil
+	return nil
 }
 
 func (p *TraktPlugin) convertToEvents(items []CalendarItem) []models.Event {
@@ -118,36 +160,19 @@ func (p *TraktPlugin) convertToEvents(items []CalendarItem) []models.Event {
 	for _, item := range items {
 		airTime, err := time.Parse(time.RFC3339, item.FirstAired)
 		if err != nil {
+			p.logger.Debug("Skipping episode without air time",
+				"show", item.Show.Title, "season", item.Episode.Season, "episode", item.Episode.Number)
 			continue
 		}
 
-		uid := fmt.Sprintf("trakt-%s-s%02de%02d-%d",
+		// The UID identifies the episode, not its air time, so a rescheduled
+		// episode is an update to the same event rather than a new one
+		uid := fmt.Sprintf("trakt-%s-s%02de%02d",
 			item.Show.IDs.Slug,
 			item.Episode.Season,
 			item.Episode.Number,
-			airTime.Unix(),
 		)
 
-		summary := fmt.Sprintf("%s - S%02dE%02d",
-			item.Show.Title,
-			item.Episode.Season,
-			item.Episode.Number,
-		)
-		if item.Episode.Title != "" {
-			summary += fmt.Sprintf(": %s", item.Episode.Title)
-		}
-
-		description := ""
-		if item.Episode.Overview != "" {
-			description = item.Episode.Overview
-		}
-		if item.Show.Network != "" {
-			if description != "" {
-				description += "\n\n"
-			}
-			description += fmt.Sprintf("Network: %s", item.Show.Network)
-		}
-
 		endTime := airTime
 		if item.Show.Runtime > 0 {
 			endTime = airTime.Add(time.Duration(item.Show.Runtime) * time.Minute)
@@ -157,13 +182,25 @@ func (p *TraktPlugin) convertToEvents(items []CalendarItem) []models.Event {
 
 		event := models.Event{
 			UID:         uid,
-			Summary:     summary,
-			Description: description,
 			StartTime:   airTime,
 			EndTime:     endTime,
 			AllDay:      false,
 			Categories:  []string{"tv", "trakt"},
+			Show:        item.Show.Title,
+			ExternalIDs: showIDs(item.Show.IDs),
+			Fields: map[string]interface{}{
+				"show":         item.Show.Title,
+				"year":         item.Show.Year,
+				"network":      item.Show.Network,
+				"runtime":      item.Show.Runtime,
+				"season":       item.Episode.Season,
+				"episode":      item.Episode.Number,
+				"episodeTitle": item.Episode.Title,
+				"overview":     item.Episode.Overview,
+				"airTime":      airTime,
+			},
 		}
+		p.templates.Render(&event)
 
 		if item.Show.IDs.Slug != "" {
 			event.URL = fmt.Sprintf("https://trakt.tv/shows/%s/seasons/%d/episodes/%d",
@@ -210,3 +247,19 @@ type ShowIDs struct {
 	IMDB  string `json:"imdb"`
 	TMDB  int    `json:"tmdb"`
 }
+
+// showIDs returns the show's identifiers in other databases, used to match
+// its episodes with events from other sources
+func showIDs(ids ShowIDs) map[string]string {
+	result := make(map[string]string)
+	if ids.Trakt > 0 {
+		result["trakt"] = strconv.Itoa(ids.Trakt)
+	}
+	if ids.IMDB != "" {
+		result["imdb"] = ids.IMDB
+	}
+	if ids.TMDB > 0 {
+		result["tmdb"] = strconv.Itoa(ids.TMDB)
+	}
+	return result
+}